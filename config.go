@@ -0,0 +1,945 @@
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelConfig holds the automatic features of a single log level.
+// Like the fields of a Rule, unset fields (nil, 0 or "") are disabled or use their default,
+// and are taken from the entry below when configurations are layered with Config.Merge.
+type LevelConfig struct {
+	AutoSource        *bool       `json:"autoSource,omitempty"`        // Automatically add source information
+	AutoCallStack     *bool       `json:"autoCallStack,omitempty"`     // Automatically add call stack information
+	MaxCallStackDepth int         `json:"maxCallStackDepth,omitempty"` // Max call stack depth (0 = use default)
+	FrameFormat       FrameFormat `json:"frameFormat,omitempty"`       // Format of the source and call stack frames ("" = FrameString)
+	PathMode          PathMode    `json:"pathMode,omitempty"`          // File paths of the source and call stack frames ("" = PathAbsolute)
+	AutoGoroutineID   *bool       `json:"autoGoroutineID,omitempty"`   // Automatically add the ID of the logging goroutine
+	AutoPprofLabels   *bool       `json:"autoPprofLabels,omitempty"`   // Automatically add the pprof labels of the entry's context
+}
+
+// inherit returns c with its unset fields taken from parent
+func (c LevelConfig) inherit(parent LevelConfig) LevelConfig {
+	if c.AutoSource == nil {
+		c.AutoSource = parent.AutoSource
+	}
+	if c.AutoCallStack == nil {
+		c.AutoCallStack = parent.AutoCallStack
+	}
+	if c.MaxCallStackDepth == 0 {
+		c.MaxCallStackDepth = parent.MaxCallStackDepth
+	}
+	if c.FrameFormat == "" {
+		c.FrameFormat = parent.FrameFormat
+	}
+	if c.PathMode == "" {
+		c.PathMode = parent.PathMode
+	}
+	if c.AutoGoroutineID == nil {
+		c.AutoGoroutineID = parent.AutoGoroutineID
+	}
+	if c.AutoPprofLabels == nil {
+		c.AutoPprofLabels = parent.AutoPprofLabels
+	}
+	return c
+}
+
+// enabled reports whether an optional feature flag is set to true
+func enabled(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+// Config holds the global configuration: the minimum level and the automatic features per level.
+// It is built with Configure, loaded with LoadConfig or ConfigureFromJSONFile, or decoded from JSON
+// as part of a larger configuration file, and applied with SetConfig.
+//
+// Example:
+//
+//	type ServiceConfig struct {
+//	    Port int         `json:"port"`
+//	    Log  zlog.Config `json:"log"`
+//	}
+type Config struct {
+	MinLevel      *slog.Level                // Entries below this level are discarded (nil = log everything)
+	Format        Format                     // Output format ("" = FormatJSON)
+	Output        string                     // "stdout", "stderr" or a file to append to ("" = keep the writer set with SetOutputWriter)
+	Levels        map[slog.Level]LevelConfig // Configuration per log level, see defaultCallStackDepths for default depths
+	Segments      map[string]Rule            // Overrides per segment pattern, see SegmentLevelConfig
+	Packages      map[string]Rule            // Overrides per Go package pattern, see PackageLevelConfig
+	Sampling      *Sampling                  // Volume limits, see SampleMessagesConfig (nil = keep every entry)
+	RateLimit     *RateLimit                 // Token bucket per level, segment and message, see RateLimitConfig (nil = none)
+	DedupWindow   time.Duration              // Window in which repeated entries are collapsed, see DedupConfig (0 = none)
+	Redaction     *Redaction                 // Sensitive keys and values removed from entries, see RedactKeysConfig (nil = none)
+	SizeLimits    *SizeLimits                // Caps on message, value and entry sizes, see MaxEntryBytesConfig (nil = none)
+	FrameFilter   *FrameFilter               // Frames removed from call stacks, see DropStdlibFramesConfig (nil = keep every frame)
+	GoroutineDump *GoroutineDump             // Entries that get a dump of every goroutine, see GoroutineDumpOnFatalConfig (nil = none)
+}
+
+// Format selects how entries are written.
+type Format string
+
+const (
+	FormatJSON Format = "json" // One JSON object per line, expected by the writers of this package
+	FormatText Format = "text" // slog's key=value text format, easier to read in a terminal
+)
+
+// parseFormat validates a format name
+func parseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case "", FormatJSON, FormatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("zlog: unknown format %q (expected json or text)", name)
+	}
+}
+
+// level returns the configuration of the given level (zero value if not configured)
+func (c Config) level(level slog.Level) LevelConfig {
+	return c.Levels[level]
+}
+
+// updateLevel applies update to the configuration of the given level
+func (c *Config) updateLevel(level slog.Level, update func(levelConf *LevelConfig)) {
+	if c.Levels == nil {
+		c.Levels = make(map[slog.Level]LevelConfig)
+	}
+	levelConf := c.Levels[level]
+	update(&levelConf)
+	c.Levels[level] = levelConf
+}
+
+// Merge returns a copy of c layered with other. Neither configuration is modified.
+// Level, segment and package entries are merged field by field: the fields set in other win, the others
+// keep c's values, so an "error" entry in other that only sets autoCallStack keeps c's autoSource for errors.
+// Other's MinLevel, Format, Output, Sampling, RateLimit, DedupWindow, Redaction, SizeLimits, FrameFilter
+// and GoroutineDump replace c's when set.
+//
+// Example:
+//
+//	defaults := zlog.Configure(zlog.AutoSourceConfig(slog.LevelError, true))
+//	config := defaults.Merge(zlog.ConfigureFromJSONFile("log-config.json")) // {"error": {"autoCallStack": true}}
+//	// errors get both the source and the call stack
+func (c Config) Merge(other Config) Config {
+	merged := c.clone()
+	if other.MinLevel != nil {
+		minLevel := *other.MinLevel
+		merged.MinLevel = &minLevel
+	}
+	if other.Format != "" {
+		merged.Format = other.Format
+	}
+	if other.Output != "" {
+		merged.Output = other.Output
+	}
+	if other.Sampling != nil {
+		sampling := other.Sampling.clone()
+		merged.Sampling = &sampling
+	}
+	if other.RateLimit != nil {
+		rateLimit := *other.RateLimit
+		merged.RateLimit = &rateLimit
+	}
+	if other.DedupWindow > 0 {
+		merged.DedupWindow = other.DedupWindow
+	}
+	if other.Redaction != nil {
+		redaction := other.Redaction.clone()
+		merged.Redaction = &redaction
+	}
+	if other.SizeLimits != nil {
+		sizeLimits := *other.SizeLimits
+		merged.SizeLimits = &sizeLimits
+	}
+	if other.FrameFilter != nil {
+		frameFilter := other.FrameFilter.clone()
+		merged.FrameFilter = &frameFilter
+	}
+	if other.GoroutineDump != nil {
+		goroutineDump := *other.GoroutineDump
+		merged.GoroutineDump = &goroutineDump
+	}
+	for level, levelConf := range other.Levels {
+		merged.updateLevel(level, func(target *LevelConfig) { *target = levelConf.inherit(*target) })
+	}
+	for pattern, rule := range other.Segments {
+		merged.updateSegment(pattern, func(target *Rule) { *target = rule.inherit(*target) })
+	}
+	for pattern, rule := range other.Packages {
+		merged.updatePackage(pattern, func(target *Rule) { *target = rule.inherit(*target) })
+	}
+	return merged
+}
+
+// clone returns a deep copy of c, so it can be stored without sharing its map with the caller
+func (c Config) clone() Config {
+	cloned := Config{Format: c.Format, Output: c.Output, DedupWindow: c.DedupWindow}
+	if c.MinLevel != nil {
+		minLevel := *c.MinLevel
+		cloned.MinLevel = &minLevel
+	}
+	if c.Levels != nil {
+		cloned.Levels = make(map[slog.Level]LevelConfig, len(c.Levels))
+		for level, levelConf := range c.Levels {
+			cloned.Levels[level] = levelConf
+		}
+	}
+	cloned.Segments = cloneRules(c.Segments)
+	cloned.Packages = cloneRules(c.Packages)
+	if c.Sampling != nil {
+		sampling := c.Sampling.clone()
+		cloned.Sampling = &sampling
+	}
+	if c.RateLimit != nil {
+		rateLimit := *c.RateLimit
+		cloned.RateLimit = &rateLimit
+	}
+	if c.Redaction != nil {
+		redaction := c.Redaction.clone()
+		cloned.Redaction = &redaction
+	}
+	if c.SizeLimits != nil {
+		sizeLimits := *c.SizeLimits
+		cloned.SizeLimits = &sizeLimits
+	}
+	if c.FrameFilter != nil {
+		frameFilter := c.FrameFilter.clone()
+		cloned.FrameFilter = &frameFilter
+	}
+	if c.GoroutineDump != nil {
+		goroutineDump := *c.GoroutineDump
+		cloned.GoroutineDump = &goroutineDump
+	}
+	return cloned
+}
+
+func cloneRules(rules map[string]Rule) map[string]Rule {
+	if rules == nil {
+		return nil
+	}
+	cloned := make(map[string]Rule, len(rules))
+	for pattern, rule := range rules {
+		cloned[pattern] = rule
+	}
+	return cloned
+}
+
+// MarshalJSON writes the level configurations keyed by lower case level name ("debug", "error", ...),
+// in the format read by UnmarshalJSON and LoadConfig.
+func (c Config) MarshalJSON() ([]byte, error) {
+	byName := make(map[string]any, len(c.Levels)+1)
+	if c.MinLevel != nil {
+		byName["minLevel"] = strings.ToLower(levelName(*c.MinLevel))
+	}
+	if c.Format != "" {
+		byName["format"] = c.Format
+	}
+	if c.Output != "" {
+		byName["output"] = c.Output
+	}
+	if len(c.Segments) > 0 {
+		byName["segments"] = c.Segments
+	}
+	if len(c.Packages) > 0 {
+		byName["packages"] = c.Packages
+	}
+	if c.Sampling != nil {
+		byName["sampling"] = c.Sampling
+	}
+	if c.RateLimit != nil {
+		byName["rateLimit"] = c.RateLimit
+	}
+	if c.DedupWindow > 0 {
+		byName["dedupWindow"] = c.DedupWindow.String()
+	}
+	if c.Redaction != nil {
+		byName["redaction"] = c.Redaction
+	}
+	if c.SizeLimits != nil {
+		byName["sizeLimits"] = c.SizeLimits
+	}
+	if c.FrameFilter != nil {
+		byName["frameFilter"] = c.FrameFilter
+	}
+	if c.GoroutineDump != nil {
+		byName["goroutineDump"] = c.GoroutineDump
+	}
+	for level, levelConf := range c.Levels {
+		byName[strings.ToLower(levelName(level))] = levelConf
+	}
+	return json.Marshal(byName)
+}
+
+// UnmarshalJSON reads a configuration in the format of LoadConfig and merges it into c like Merge.
+// It is as strict as LoadConfig: invalid documents are returned as a *ConfigError and leave c unchanged.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	config, err := parseConfig(data)
+	if err != nil {
+		return err
+	}
+	*c = c.Merge(config)
+	return nil
+}
+
+// schemaKey may reference the JSON Schema of the file for editors, it is ignored when loading.
+const schemaKey = "$schema"
+
+// ConfigError describes an invalid configuration file and where the problem is.
+type ConfigError struct {
+	Path   string // Path of the file, empty when the configuration did not come from a file
+	Line   int    // 1-based line of the offending key or value (0 = unknown)
+	Column int    // 1-based byte column of the offending key or value (0 = unknown)
+	Err    error
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString("zlog: ")
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", e.Line, e.Column)
+	}
+	if b.Len() > len("zlog: ") {
+		b.WriteString(" ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigDecoder converts a configuration file in another format to the JSON format read by LoadConfig.
+type ConfigDecoder = func(data []byte) ([]byte, error)
+
+var (
+	configFormatsMu sync.RWMutex
+	configFormats   = map[string]ConfigDecoder{}
+
+	// Packages providing decoders for well-known extensions, suggested when none is registered
+	configFormatPackages = map[string]string{
+		".yaml": "github.com/GokselKUCUKSAHIN/zlog/yamlconfig",
+		".yml":  "github.com/GokselKUCUKSAHIN/zlog/yamlconfig",
+		".toml": "github.com/GokselKUCUKSAHIN/zlog/tomlconfig",
+	}
+)
+
+// RegisterConfigFormat makes LoadConfig, ConfigureFromFile and WatchConfigFile read files with the
+// given extension (e.g. ".yaml") through decode. The decoded document has the same schema as the JSON file.
+// The yamlconfig and tomlconfig packages register themselves when imported, keeping this package free
+// of dependencies:
+//
+//	import _ "github.com/GokselKUCUKSAHIN/zlog/yamlconfig"
+func RegisterConfigFormat(extension string, decode ConfigDecoder) {
+	configFormatsMu.Lock()
+	defer configFormatsMu.Unlock()
+	configFormats[strings.ToLower(extension)] = decode
+}
+
+// configDecoder returns the decoder for the extension of path, nil for JSON
+func configDecoder(path string) (ConfigDecoder, error) {
+	extension := strings.ToLower(filepath.Ext(path))
+	configFormatsMu.RLock()
+	decode, ok := configFormats[extension]
+	configFormatsMu.RUnlock()
+	if ok {
+		return decode, nil
+	}
+	if pkg, known := configFormatPackages[extension]; known {
+		return nil, fmt.Errorf("zlog: no decoder registered for %s config files, import _ %q", extension, pkg)
+	}
+	return nil, nil
+}
+
+// LoadConfig reads and validates the configuration file at path.
+// Unlike ConfigureFromJSONFile it does not fall back to defaults: unknown keys, unknown level
+// names, values of the wrong type and negative depths are returned as a *ConfigError
+// pointing at the line and column of the problem. The path is used as is.
+//
+// Files are read as JSON unless their extension was registered with RegisterConfigFormat
+// (".yaml", ".yml" and ".toml" by the yamlconfig and tomlconfig packages). Errors in such
+// files name the offending key, their line and column refer to the original file only
+// for syntax errors reported by the decoder.
+// The file format is described by zlog-config.schema.json in the repository.
+//
+// Example:
+//
+//	config, err := zlog.LoadConfig("log-config.json")
+//	if err != nil {
+//	    log.Fatal(err) // zlog: log-config.json:4:9: unknown field "autoSorce" in "error"
+//	}
+//	zlog.SetConfig(config)
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	return parseConfigFile(path, data)
+}
+
+// parseConfigFile decodes data according to the extension of path and parses it,
+// recording the path in returned errors
+func parseConfigFile(path string, data []byte) (Config, error) {
+	decode, err := configDecoder(path)
+	if err != nil {
+		return Config{}, err
+	}
+	if decode != nil {
+		if data, err = decode(data); err != nil {
+			return Config{}, &ConfigError{Path: path, Err: err}
+		}
+	}
+
+	config, err := parseConfig(data)
+	var configErr *ConfigError
+	if errors.As(err, &configErr) {
+		configErr.Path = path
+		if decode != nil {
+			// Positions refer to the converted JSON, not to the file
+			configErr.Line, configErr.Column = 0, 0
+		}
+	}
+	return config, err
+}
+
+// parseConfig strictly decodes and validates a JSON configuration
+func parseConfig(data []byte) (Config, error) {
+	// Check the syntax first, so the walk below only has to deal with the structure
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return Config{}, newConfigError(data, int(syntaxErr.Offset)-1, err)
+		}
+		return Config{}, &ConfigError{Err: err}
+	}
+
+	parser := configParser{data: data, decoder: json.NewDecoder(bytes.NewReader(data))}
+	var config Config
+	if err := parser.parse(&config); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// configParser walks a syntactically valid configuration token by token,
+// so every error can be reported at the position of its key or value.
+type configParser struct {
+	data    []byte
+	decoder *json.Decoder
+}
+
+func (p *configParser) parse(config *Config) error {
+	if err := p.expectObject(skipSpace(p.data, 0), "configuration"); err != nil {
+		return err
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		switch key {
+		case schemaKey:
+			var discard json.RawMessage
+			_ = p.decoder.Decode(&discard)
+		case "minLevel":
+			var name string
+			if err := p.decoder.Decode(&name); err != nil {
+				return newConfigError(p.data, valueStart, errors.New("minLevel must be a level name"))
+			}
+			minLevel, err := parseLevel(name)
+			if err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("unknown minLevel %q", name))
+			}
+			config.MinLevel = &minLevel
+		case "format":
+			var name string
+			if err := p.decoder.Decode(&name); err != nil {
+				return newConfigError(p.data, valueStart, errors.New("format must be a string"))
+			}
+			format, err := parseFormat(name)
+			if err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("unknown format %q (expected json or text)", name))
+			}
+			config.Format = format
+		case "output":
+			if err := p.decoder.Decode(&config.Output); err != nil {
+				return newConfigError(p.data, valueStart, errors.New("output must be stdout, stderr or a file path"))
+			}
+		case "segments":
+			if err := p.parseRules(key, valueStart, config.updateSegment); err != nil {
+				return err
+			}
+		case "packages":
+			if err := p.parseRules(key, valueStart, config.updatePackage); err != nil {
+				return err
+			}
+		case "sampling":
+			sampling := Sampling{}
+			if err := p.parseSampling(valueStart, &sampling); err != nil {
+				return err
+			}
+			config.Sampling = &sampling
+		case "rateLimit":
+			rateLimit := RateLimit{}
+			if err := p.parseRateLimit(valueStart, &rateLimit); err != nil {
+				return err
+			}
+			config.RateLimit = &rateLimit
+		case "dedupWindow":
+			window, err := p.parseDuration(key, valueStart)
+			if err != nil {
+				return err
+			}
+			config.DedupWindow = window
+		case "redaction":
+			redaction := Redaction{}
+			if err := p.parseRedaction(valueStart, &redaction); err != nil {
+				return err
+			}
+			config.Redaction = &redaction
+		case "sizeLimits":
+			sizeLimits := SizeLimits{}
+			if err := p.parseSizeLimits(valueStart, &sizeLimits); err != nil {
+				return err
+			}
+			config.SizeLimits = &sizeLimits
+		case "frameFilter":
+			frameFilter := FrameFilter{}
+			if err := p.parseFrameFilter(valueStart, &frameFilter); err != nil {
+				return err
+			}
+			config.FrameFilter = &frameFilter
+		case "goroutineDump":
+			goroutineDump := GoroutineDump{}
+			if err := p.parseGoroutineDump(valueStart, &goroutineDump); err != nil {
+				return err
+			}
+			config.GoroutineDump = &goroutineDump
+		default:
+			level, err := parseLevel(key)
+			if err != nil {
+				return newConfigError(p.data, keyStart, fmt.Errorf("unknown level %q", key))
+			}
+			levelConf := config.level(level)
+			if err = p.parseLevelConfig(key, valueStart, &levelConf); err != nil {
+				return err
+			}
+			config.updateLevel(level, func(target *LevelConfig) { *target = levelConf })
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	return nil
+}
+
+func (p *configParser) parseLevelConfig(levelKey string, start int, levelConf *LevelConfig) error {
+	if err := p.expectObject(start, fmt.Sprintf("%q", levelKey)); err != nil {
+		return err
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		switch key {
+		case "autoSource", "autoCallStack", "autoGoroutineID", "autoPprofLabels":
+			var enabled bool
+			if err := p.decoder.Decode(&enabled); err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("%s.%s must be a boolean", levelKey, key))
+			}
+			switch key {
+			case "autoSource":
+				levelConf.AutoSource = &enabled
+			case "autoCallStack":
+				levelConf.AutoCallStack = &enabled
+			case "autoGoroutineID":
+				levelConf.AutoGoroutineID = &enabled
+			default:
+				levelConf.AutoPprofLabels = &enabled
+			}
+		case "maxCallStackDepth":
+			var depth int
+			if err := p.decoder.Decode(&depth); err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("%s.maxCallStackDepth must be an integer", levelKey))
+			}
+			if depth < 0 {
+				return newConfigError(p.data, valueStart, fmt.Errorf("%s.maxCallStackDepth must not be negative, got %d", levelKey, depth))
+			}
+			levelConf.MaxCallStackDepth = depth
+		case "frameFormat":
+			var name string
+			if err := p.decoder.Decode(&name); err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("%s.frameFormat must be a string", levelKey))
+			}
+			format, err := parseFrameFormat(name)
+			if err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("unknown frame format %q for %q (expected string or structured)", name, levelKey))
+			}
+			levelConf.FrameFormat = format
+		case "pathMode":
+			var name string
+			if err := p.decoder.Decode(&name); err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("%s.pathMode must be a string", levelKey))
+			}
+			mode, err := parsePathMode(name)
+			if err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("unknown path mode %q for %q (expected absolute, module, gopath or base)", name, levelKey))
+			}
+			levelConf.PathMode = mode
+		default:
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in %q (expected autoSource, autoCallStack, maxCallStackDepth, frameFormat, pathMode, autoGoroutineID or autoPprofLabels)", key, levelKey))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	return nil
+}
+
+// parseRules parses the rules of section ("segments" or "packages") keyed by pattern
+func (p *configParser) parseRules(section string, start int, update func(pattern string, update func(rule *Rule))) error {
+	if err := p.expectObject(start, section); err != nil {
+		return err
+	}
+	kind := strings.TrimSuffix(section, "s")
+	for p.decoder.More() {
+		pattern, _, valueStart := p.nextKey()
+		if err := p.expectObject(valueStart, fmt.Sprintf("%s %q", kind, pattern)); err != nil {
+			return err
+		}
+		rule := Rule{}
+		for p.decoder.More() {
+			key, keyStart, valueStart := p.nextKey()
+			switch key {
+			case "level":
+				var name string
+				if err := p.decoder.Decode(&name); err != nil {
+					return newConfigError(p.data, valueStart, fmt.Errorf("%s.%s.level must be a level name", section, pattern))
+				}
+				level, err := parseLevel(name)
+				if err != nil {
+					return newConfigError(p.data, valueStart, fmt.Errorf("unknown level %q for %s %q", name, kind, pattern))
+				}
+				rule.Level = &level
+			case "autoSource", "autoCallStack":
+				var enabled bool
+				if err := p.decoder.Decode(&enabled); err != nil {
+					return newConfigError(p.data, valueStart, fmt.Errorf("%s.%s.%s must be a boolean", section, pattern, key))
+				}
+				if key == "autoSource" {
+					rule.AutoSource = &enabled
+				} else {
+					rule.AutoCallStack = &enabled
+				}
+			case "maxCallStackDepth":
+				var depth int
+				if err := p.decoder.Decode(&depth); err != nil || depth < 0 {
+					return newConfigError(p.data, valueStart, fmt.Errorf("%s.%s.maxCallStackDepth must be a non-negative integer", section, pattern))
+				}
+				rule.MaxCallStackDepth = depth
+			default:
+				return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in %s %q (expected level, autoSource, autoCallStack or maxCallStackDepth)", key, kind, pattern))
+			}
+		}
+		_, _ = p.decoder.Token() // Closing brace of the rule
+		update(pattern, func(target *Rule) { *target = rule })
+	}
+	_, _ = p.decoder.Token() // Closing brace of the section
+	return nil
+}
+
+// parseSampling parses the "sampling" section
+func (p *configParser) parseSampling(start int, sampling *Sampling) error {
+	if err := p.expectObject(start, "sampling"); err != nil {
+		return err
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		switch key {
+		case "interval", "summaryInterval":
+			interval, err := p.parseDuration("sampling."+key, valueStart)
+			if err != nil {
+				return err
+			}
+			if key == "interval" {
+				sampling.Interval = interval
+			} else {
+				sampling.SummaryInterval = interval
+			}
+		case "first", "thereafter":
+			var count int
+			if err := p.decoder.Decode(&count); err != nil || count < 0 {
+				return newConfigError(p.data, valueStart, fmt.Errorf("sampling.%s must be a non-negative integer", key))
+			}
+			if key == "first" {
+				sampling.First = count
+			} else {
+				sampling.Thereafter = count
+			}
+		case "rates":
+			if err := p.expectObject(valueStart, "sampling.rates"); err != nil {
+				return err
+			}
+			sampling.Rates = make(map[slog.Level]float64)
+			for p.decoder.More() {
+				name, nameStart, rateStart := p.nextKey()
+				level, err := parseLevel(name)
+				if err != nil {
+					return newConfigError(p.data, nameStart, fmt.Errorf("unknown level %q in sampling.rates", name))
+				}
+				var rate float64
+				if err := p.decoder.Decode(&rate); err != nil || rate < 0 || rate > 1 {
+					return newConfigError(p.data, rateStart, fmt.Errorf("sampling.rates.%s must be a number between 0 and 1", name))
+				}
+				sampling.Rates[level] = rate
+			}
+			_, _ = p.decoder.Token() // Closing brace of the rates
+		case "budgets":
+			if err := p.expectObject(valueStart, "sampling.budgets"); err != nil {
+				return err
+			}
+			sampling.Budgets = make(map[string]SegmentBudget)
+			for p.decoder.More() {
+				pattern, _, budgetStart := p.nextKey()
+				if err := p.expectObject(budgetStart, fmt.Sprintf("budget %q", pattern)); err != nil {
+					return err
+				}
+				budget := SegmentBudget{}
+				for p.decoder.More() {
+					field, fieldStart, fieldValueStart := p.nextKey()
+					switch field {
+					case "count":
+						if err := p.decoder.Decode(&budget.Count); err != nil || budget.Count < 0 {
+							return newConfigError(p.data, fieldValueStart, fmt.Errorf("sampling.budgets.%s.count must be a non-negative integer", pattern))
+						}
+					case "interval":
+						interval, err := p.parseDuration(fmt.Sprintf("sampling.budgets.%s.interval", pattern), fieldValueStart)
+						if err != nil {
+							return err
+						}
+						budget.Interval = interval
+					default:
+						return newConfigError(p.data, fieldStart, fmt.Errorf("unknown field %q in budget %q (expected count or interval)", field, pattern))
+					}
+				}
+				_, _ = p.decoder.Token() // Closing brace of the budget
+				if budget.Interval <= 0 {
+					return newConfigError(p.data, budgetStart, fmt.Errorf("budget %q needs an interval", pattern))
+				}
+				sampling.Budgets[pattern] = budget
+			}
+			_, _ = p.decoder.Token() // Closing brace of the budgets
+		default:
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in sampling (expected interval, first, thereafter, rates, budgets or summaryInterval)", key))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace of the section
+	return nil
+}
+
+// parseRateLimit parses the "rateLimit" section
+func (p *configParser) parseRateLimit(start int, rateLimit *RateLimit) error {
+	if err := p.expectObject(start, "rateLimit"); err != nil {
+		return err
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		switch key {
+		case "every":
+			every, err := p.parseDuration("rateLimit.every", valueStart)
+			if err != nil {
+				return err
+			}
+			rateLimit.Every = every
+		case "burst":
+			if err := p.decoder.Decode(&rateLimit.Burst); err != nil || rateLimit.Burst < 1 {
+				return newConfigError(p.data, valueStart, errors.New("rateLimit.burst must be a positive integer"))
+			}
+		default:
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in rateLimit (expected every or burst)", key))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	if rateLimit.Every <= 0 || rateLimit.Burst < 1 {
+		return newConfigError(p.data, start, errors.New("rateLimit needs every and burst"))
+	}
+	return nil
+}
+
+// parseRedaction parses the "redaction" section, checking that the patterns compile
+func (p *configParser) parseRedaction(start int, redaction *Redaction) error {
+	if err := p.expectObject(start, "redaction"); err != nil {
+		return err
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		switch key {
+		case "keys", "patterns":
+			var values []string
+			if err := p.decoder.Decode(&values); err != nil {
+				return newConfigError(p.data, valueStart, fmt.Errorf("redaction.%s must be an array of strings", key))
+			}
+			if key == "keys" {
+				redaction.Keys = values
+				continue
+			}
+			for _, pattern := range values {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return newConfigError(p.data, valueStart, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err))
+				}
+			}
+			redaction.Patterns = values
+		case "hashKey":
+			var hashKey string
+			if err := p.decoder.Decode(&hashKey); err != nil {
+				return newConfigError(p.data, valueStart, errors.New("redaction.hashKey must be a string"))
+			}
+			redaction.HashKey = []byte(hashKey)
+		default:
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in redaction (expected keys, patterns or hashKey)", key))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	return nil
+}
+
+// parseFrameFilter parses the "frameFilter" section
+func (p *configParser) parseFrameFilter(start int, filter *FrameFilter) error {
+	if err := p.expectObject(start, "frameFilter"); err != nil {
+		return err
+	}
+	fields := map[string]*bool{
+		"dropRuntime":      &filter.DropRuntime,
+		"dropStdlib":       &filter.DropStdlib,
+		"collapsePackages": &filter.CollapsePackages,
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		if key == "dropPackages" {
+			if err := p.decoder.Decode(&filter.DropPackages); err != nil {
+				return newConfigError(p.data, valueStart, errors.New("frameFilter.dropPackages must be an array of strings"))
+			}
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in frameFilter (expected dropRuntime, dropStdlib, dropPackages or collapsePackages)", key))
+		}
+		if err := p.decoder.Decode(field); err != nil {
+			return newConfigError(p.data, valueStart, fmt.Errorf("frameFilter.%s must be a boolean", key))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	return nil
+}
+
+// parseGoroutineDump parses the "goroutineDump" section
+func (p *configParser) parseGoroutineDump(start int, dump *GoroutineDump) error {
+	if err := p.expectObject(start, "goroutineDump"); err != nil {
+		return err
+	}
+	fields := map[string]*bool{
+		"onFatal": &dump.OnFatal,
+		"onAlert": &dump.OnAlert,
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		field, ok := fields[key]
+		if !ok {
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in goroutineDump (expected onFatal or onAlert)", key))
+		}
+		if err := p.decoder.Decode(field); err != nil {
+			return newConfigError(p.data, valueStart, fmt.Errorf("goroutineDump.%s must be a boolean", key))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	return nil
+}
+
+// parseSizeLimits parses the "sizeLimits" section
+func (p *configParser) parseSizeLimits(start int, limits *SizeLimits) error {
+	if err := p.expectObject(start, "sizeLimits"); err != nil {
+		return err
+	}
+	fields := map[string]*int{
+		"maxMessageLength":    &limits.MaxMessageLength,
+		"maxStringLength":     &limits.MaxStringLength,
+		"maxCollectionLength": &limits.MaxCollectionLength,
+		"maxNestingDepth":     &limits.MaxNestingDepth,
+		"maxEntryBytes":       &limits.MaxEntryBytes,
+	}
+	for p.decoder.More() {
+		key, keyStart, valueStart := p.nextKey()
+		field, ok := fields[key]
+		if !ok {
+			return newConfigError(p.data, keyStart, fmt.Errorf("unknown field %q in sizeLimits (expected maxMessageLength, maxStringLength, maxCollectionLength, maxNestingDepth or maxEntryBytes)", key))
+		}
+		if err := p.decoder.Decode(field); err != nil || *field < 0 {
+			return newConfigError(p.data, valueStart, fmt.Errorf("sizeLimits.%s must be a non-negative integer", key))
+		}
+	}
+	_, _ = p.decoder.Token() // Closing brace
+	return nil
+}
+
+// parseDuration parses a positive duration string such as "1s" or "1m30s"
+func (p *configParser) parseDuration(field string, start int) (time.Duration, error) {
+	var value string
+	if err := p.decoder.Decode(&value); err != nil {
+		return 0, newConfigError(p.data, start, fmt.Errorf("%s must be a duration string such as \"1s\"", field))
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, newConfigError(p.data, start, fmt.Errorf("%s must be a positive duration such as \"1s\", got %q", field, value))
+	}
+	return duration, nil
+}
+
+// expectObject consumes the opening brace of an object starting at offset start
+func (p *configParser) expectObject(start int, what string) error {
+	token, err := p.decoder.Token()
+	if err != nil || token != json.Delim('{') {
+		return newConfigError(p.data, start, fmt.Errorf("%s must be an object", what))
+	}
+	return nil
+}
+
+// nextKey consumes an object key and returns it with the offsets of the key and of its value
+func (p *configParser) nextKey() (key string, keyStart, valueStart int) {
+	token, _ := p.decoder.Token()
+	key, _ = token.(string)
+	keyEnd := int(p.decoder.InputOffset())
+
+	// The decoder stops right after the closing quote, walk back to the opening one
+	keyStart = keyEnd - 1
+	for keyStart > 0 {
+		keyStart--
+		if p.data[keyStart] == '"' && (keyStart == 0 || p.data[keyStart-1] != '\\') {
+			break
+		}
+	}
+	valueStart = skipSpace(p.data, keyEnd)
+	if valueStart < len(p.data) && p.data[valueStart] == ':' {
+		valueStart = skipSpace(p.data, valueStart+1)
+	}
+	return key, keyStart, valueStart
+}
+
+func skipSpace(data []byte, offset int) int {
+	for offset < len(data) && strings.IndexByte(" \t\r\n", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// newConfigError wraps err with the line and column of the byte at offset
+func newConfigError(data []byte, offset int, err error) *ConfigError {
+	offset = max(0, min(offset, len(data)))
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return &ConfigError{Line: line, Column: column, Err: err}
+}
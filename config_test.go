@@ -0,0 +1,404 @@
+package zlog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestLoadConfig tests loading a valid configuration file
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{
+    "$schema": "./zlog-config.schema.json",
+    "minLevel": "info",
+    "error": {"autoSource": true, "autoCallStack": true, "maxCallStackDepth": 8},
+    "WARN": {"autoSource": true}
+}`)
+
+	config, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.MinLevel == nil || *config.MinLevel != slog.LevelInfo {
+		t.Errorf("Expected minLevel info, got %v", config.MinLevel)
+	}
+	if levelConf := config.Levels[slog.LevelError]; !enabled(levelConf.AutoSource) || !enabled(levelConf.AutoCallStack) || levelConf.MaxCallStackDepth != 8 {
+		t.Errorf("Unexpected error level config: %+v", levelConf)
+	}
+	if !enabled(config.Levels[slog.LevelWarn].AutoSource) {
+		t.Error("Expected level names to be case-insensitive")
+	}
+}
+
+// TestLoadConfigExampleFile tests that the example file in the repository is valid
+func TestLoadConfigExampleFile(t *testing.T) {
+	if _, err := zlog.LoadConfig("example-log-config.json"); err != nil {
+		t.Errorf("Expected example config to be valid: %v", err)
+	}
+}
+
+// TestLoadConfigErrors tests that invalid files are rejected with their position, by LoadConfig and json.Unmarshal alike
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+		message string
+	}{
+		{
+			name:    "syntax error",
+			content: "{\n    \"error\": {\"autoSource\": true,}\n}",
+			line:    2, column: 34,
+			message: "invalid character '}'",
+		},
+		{
+			name:    "unknown field",
+			content: "{\n    \"error\": {\n        \"autoSorce\": true\n    }\n}",
+			line:    3, column: 9,
+			message: `unknown field "autoSorce" in "error"`,
+		},
+		{
+			name:    "unknown level",
+			content: "{\"eror\": {\"autoSource\": true}}",
+			line:    1, column: 2,
+			message: `unknown level "eror"`,
+		},
+		{
+			name:    "wrong type",
+			content: "{\"error\": {\"autoSource\": \"yes\"}}",
+			line:    1, column: 26,
+			message: "error.autoSource must be a boolean",
+		},
+		{
+			name:    "negative depth",
+			content: "{\n\"debug\": {\"maxCallStackDepth\": -3}\n}",
+			line:    2, column: 32,
+			message: "debug.maxCallStackDepth must not be negative, got -3",
+		},
+		{
+			name:    "unknown frame format",
+			content: "{\"error\": {\"frameFormat\": \"xml\"}}",
+			line:    1, column: 27,
+			message: `unknown frame format "xml" for "error"`,
+		},
+		{
+			name:    "unknown goroutine dump field",
+			content: "{\"goroutineDump\": {\"onPanic\": true}}",
+			line:    1, column: 20,
+			message: `unknown field "onPanic" in goroutineDump (expected onFatal or onAlert)`,
+		},
+		{
+			name:    "level not an object",
+			content: "{\"error\": true}",
+			line:    1, column: 11,
+			message: `"error" must be an object`,
+		},
+		{
+			name:    "unknown minLevel",
+			content: "{\"minLevel\": \"verbose\"}",
+			line:    1, column: 14,
+			message: `unknown minLevel "verbose"`,
+		},
+		{
+			name:    "unknown format",
+			content: "{\"format\": \"xml\"}",
+			line:    1, column: 12,
+			message: `unknown format "xml"`,
+		},
+		{
+			name:    "not an object",
+			content: "[]",
+			line:    1, column: 1,
+			message: "configuration must be an object",
+		},
+	}
+
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, "config.json")
+			writeConfigFile(t, configPath, tt.content)
+
+			_, err := zlog.LoadConfig(configPath)
+			var configErr *zlog.ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected *ConfigError, got %v", err)
+			}
+			if configErr.Path != configPath || configErr.Line != tt.line || configErr.Column != tt.column {
+				t.Errorf("Expected %s:%d:%d, got %s:%d:%d", configPath, tt.line, tt.column, configErr.Path, configErr.Line, configErr.Column)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %q", tt.message, err.Error())
+			}
+
+			// Config.UnmarshalJSON rejects the same documents
+			config := zlog.Configure(zlog.AutoSourceConfig(slog.LevelError, true))
+			if err := json.Unmarshal([]byte(tt.content), &config); err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected json.Unmarshal error containing %q, got %v", tt.message, err)
+			}
+			if data, _ := json.Marshal(config); string(data) != `{"error":{"autoSource":true}}` {
+				t.Errorf("Expected the configuration to be unchanged, got %s", data)
+			}
+		})
+	}
+
+	if _, err := zlog.LoadConfig(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+// TestConfigSchema tests that the JSON Schema describes every level configuration field
+func TestConfigSchema(t *testing.T) {
+	data, err := os.ReadFile("zlog-config.schema.json")
+	if err != nil {
+		t.Fatalf("Failed to read schema: %v", err)
+	}
+	var schema struct {
+		Defs struct {
+			LevelConfig struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"levelConfig"`
+		} `json:"$defs"`
+	}
+	if err = json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	configJSON, err := json.Marshal(zlog.Configure(
+		zlog.AutoSourceConfig(slog.LevelError, true),
+		zlog.AutoCallStackConfig(slog.LevelError, false),
+		zlog.MaxCallStackDepthConfig(slog.LevelError, 8),
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.PathModeConfig(slog.LevelError, zlog.PathModule),
+		zlog.AutoGoroutineIDConfig(slog.LevelError, true),
+		zlog.AutoPprofLabelsConfig(slog.LevelError, true),
+	))
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	var marshalled map[string]map[string]any
+	if err = json.Unmarshal(configJSON, &marshalled); err != nil {
+		t.Fatalf("Failed to parse marshalled config: %v", err)
+	}
+
+	var fields, schemaFields []string
+	for field := range marshalled["error"] {
+		fields = append(fields, field)
+	}
+	for field := range schema.Defs.LevelConfig.Properties {
+		schemaFields = append(schemaFields, field)
+	}
+	sort.Strings(fields)
+	sort.Strings(schemaFields)
+	if strings.Join(fields, ",") != strings.Join(schemaFields, ",") {
+		t.Errorf("Schema fields %v do not match config fields %v", schemaFields, fields)
+	}
+}
+
+// TestConfigJSONRoundTrip tests that a marshalled configuration decodes to the same configuration
+func TestConfigJSONRoundTrip(t *testing.T) {
+	original := zlog.Configure(
+		zlog.MinLevelConfig(zlog.LevelNotice),
+		zlog.FormatConfig(zlog.FormatText),
+		zlog.OutputConfig("stderr"),
+		zlog.AutoSourceConfig(slog.LevelError, true),
+		zlog.AutoCallStackConfig(slog.LevelError, true),
+		zlog.MaxCallStackDepthConfig(slog.LevelError, 12),
+		zlog.AutoSourceConfig(zlog.LevelTrace, true),
+		zlog.AutoSourceConfig(slog.LevelInfo+1, true), // Unregistered level, written as "info+1"
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.PathModeConfig(slog.LevelError, zlog.PathGoPath),
+		zlog.AutoGoroutineIDConfig(slog.LevelInfo, true),
+		zlog.DropStdlibFramesConfig(),
+		zlog.DropFramePackagesConfig("github.com/acme/mux/..."),
+		zlog.CollapseFramesConfig(),
+		zlog.GoroutineDumpOnFatalConfig(true),
+	)
+
+	type serviceConfig struct {
+		Port int         `json:"port"`
+		Log  zlog.Config `json:"log"`
+	}
+	data, err := json.Marshal(serviceConfig{Port: 8080, Log: original})
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+
+	var decoded serviceConfig
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v\nJSON: %s", err, data)
+	}
+	if !reflect.DeepEqual(decoded.Log, original) {
+		t.Errorf("Round trip changed the config:\noriginal: %+v\ndecoded:  %+v\nJSON: %s", original, decoded.Log, data)
+	}
+
+	logData, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	writeConfigFile(t, configPath, string(logData))
+	loaded, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Expected marshalled config to be loadable: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, original) {
+		t.Errorf("LoadConfig changed the config:\noriginal: %+v\nloaded:   %+v", original, loaded)
+	}
+}
+
+// TestConfigMerge tests layering configurations
+func TestConfigMerge(t *testing.T) {
+	defaults := zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelDebug),
+		zlog.AutoSourceConfig(slog.LevelError, true),
+		zlog.AutoSourceConfig(slog.LevelWarn, true),
+	)
+	file := zlog.Configure(
+		zlog.AutoCallStackConfig(slog.LevelError, true),
+	)
+	overrides := zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelWarn),
+	)
+
+	merged := defaults.Merge(file).Merge(overrides)
+
+	if merged.MinLevel == nil || *merged.MinLevel != slog.LevelWarn {
+		t.Errorf("Expected minLevel warn, got %v", merged.MinLevel)
+	}
+	if levelConf := merged.Levels[slog.LevelError]; !enabled(levelConf.AutoSource) || !enabled(levelConf.AutoCallStack) {
+		t.Errorf("Expected error entries of both layers to be merged, got %+v", levelConf)
+	}
+	if !enabled(merged.Levels[slog.LevelWarn].AutoSource) {
+		t.Error("Expected warn entry from defaults to be kept")
+	}
+
+	if *defaults.MinLevel != slog.LevelDebug || enabled(defaults.Levels[slog.LevelError].AutoCallStack) {
+		t.Error("Merge modified its receiver")
+	}
+	if _, ok := file.Levels[slog.LevelWarn]; ok {
+		t.Error("Merge modified its argument")
+	}
+	if unset := defaults.Merge(zlog.Configure()); *unset.MinLevel != slog.LevelDebug {
+		t.Error("Expected unset minLevel to keep the receiver's")
+	}
+}
+
+// TestConfigMergeLevelFields tests that layers setting different fields of the same entry are merged
+func TestConfigMergeLevelFields(t *testing.T) {
+	dir := t.TempDir()
+	basePath := filepath.Join(dir, "base.json")
+	writeConfigFile(t, basePath, `{
+    "error": {"autoSource": true, "maxCallStackDepth": 8, "frameFormat": "structured", "pathMode": "module"},
+    "warn": {"autoSource": true, "autoCallStack": true},
+    "segments": {"payment": {"level": "debug", "autoSource": true}}
+}`)
+	overlayPath := filepath.Join(dir, "overlay.json")
+	writeConfigFile(t, overlayPath, `{
+    "error": {"autoCallStack": true},
+    "warn": {"autoCallStack": false},
+    "segments": {"payment": {"autoCallStack": true}}
+}`)
+	base, err := zlog.LoadConfig(basePath)
+	if err != nil {
+		t.Fatalf("Failed to load base config: %v", err)
+	}
+	overlay, err := zlog.LoadConfig(overlayPath)
+	if err != nil {
+		t.Fatalf("Failed to load overlay config: %v", err)
+	}
+
+	merged := base.Merge(overlay)
+	errorConf := merged.Levels[slog.LevelError]
+	if !enabled(errorConf.AutoSource) || !enabled(errorConf.AutoCallStack) || errorConf.MaxCallStackDepth != 8 ||
+		errorConf.FrameFormat != zlog.FrameStructured || errorConf.PathMode != zlog.PathModule {
+		t.Errorf("Expected the error fields of both layers, got %+v", errorConf)
+	}
+	if warnConf := merged.Levels[slog.LevelWarn]; !enabled(warnConf.AutoSource) || warnConf.AutoCallStack == nil || *warnConf.AutoCallStack {
+		t.Errorf("Expected the overlay to turn off autoCallStack for warn only, got %+v", warnConf)
+	}
+	if payment := merged.Segments["payment"]; payment.Level == nil || *payment.Level != slog.LevelDebug || !enabled(payment.AutoSource) || !enabled(payment.AutoCallStack) {
+		t.Errorf("Expected the payment rule fields of both layers, got %+v", payment)
+	}
+
+	// UseConfig merges the same way
+	config := zlog.Configure(zlog.AutoGoroutineIDConfig(slog.LevelError, true), zlog.UseConfig(overlay))
+	if levelConf := config.Levels[slog.LevelError]; !enabled(levelConf.AutoGoroutineID) || !enabled(levelConf.AutoCallStack) {
+		t.Errorf("Expected UseConfig to keep earlier fields, got %+v", levelConf)
+	}
+}
+
+// enabled reports whether an optional flag of a LevelConfig or Rule is set to true
+func enabled(flag *bool) bool {
+	return flag != nil && *flag
+}
+
+// TestCurrentConfig tests reading back the active configuration
+func TestCurrentConfig(t *testing.T) {
+	t.Cleanup(func() { zlog.SetConfig(zlog.Configure()) })
+
+	config := zlog.Configure(zlog.AutoSourceConfig(slog.LevelError, true))
+	zlog.SetConfig(config)
+
+	// Neither the configuration passed in nor the returned copy may change the active one
+	config.Levels[slog.LevelError] = zlog.LevelConfig{}
+	current := zlog.CurrentConfig()
+	if !enabled(current.Levels[slog.LevelError].AutoSource) {
+		t.Fatalf("Expected active autoSource for error, got %+v", current)
+	}
+	current.Levels[slog.LevelError] = zlog.LevelConfig{}
+	if !enabled(zlog.CurrentConfig().Levels[slog.LevelError].AutoSource) {
+		t.Error("Modifying the returned config changed the active configuration")
+	}
+}
+
+// TestLoadConfigFormats tests format detection by file extension
+func TestLoadConfigFormats(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "log-config.yaml")
+	writeConfigFile(t, yamlPath, "error:\n  autoSource: true\n")
+	if _, err := zlog.LoadConfig(yamlPath); err == nil || !strings.Contains(err.Error(), "zlog/yamlconfig") {
+		t.Errorf("Expected error suggesting the yamlconfig package, got %v", err)
+	}
+
+	// A registered decoder is used for its extension, its output is validated as usual
+	zlog.RegisterConfigFormat(".kv", func(data []byte) ([]byte, error) {
+		level, setting, _ := strings.Cut(strings.TrimSpace(string(data)), ".")
+		return []byte(`{"` + level + `": {"` + setting + `": true}}`), nil
+	})
+	kvPath := filepath.Join(dir, "log-config.KV")
+	writeConfigFile(t, kvPath, "warn.autoSource")
+	config, err := zlog.LoadConfig(kvPath)
+	if err != nil || !enabled(config.Levels[slog.LevelWarn].AutoSource) {
+		t.Errorf("Expected registered decoder to be used, got %+v, %v", config, err)
+	}
+	writeConfigFile(t, kvPath, "warn.autoSorce")
+	var configErr *zlog.ConfigError
+	if _, err = zlog.LoadConfig(kvPath); !errors.As(err, &configErr) || configErr.Path != kvPath || configErr.Line != 0 {
+		t.Errorf("Expected positionless error for converted file, got %v", err)
+	}
+
+	// Other extensions are read as JSON, also by ConfigureFromJSONFile which no longer appends ".json"
+	confPath := filepath.Join(dir, "log.conf")
+	writeConfigFile(t, confPath, `{"error": {"autoCallStack": true}}`)
+	if config, err = zlog.LoadConfig(confPath); err != nil || !enabled(config.Levels[slog.LevelError].AutoCallStack) {
+		t.Errorf("Expected JSON to be read from .conf, got %+v, %v", config, err)
+	}
+	if config = zlog.ConfigureFromJSONFile(confPath); !enabled(config.Levels[slog.LevelError].AutoCallStack) {
+		t.Errorf("Expected ConfigureFromJSONFile to keep the path, got %+v", config)
+	}
+	if config = zlog.ConfigureFromFile(confPath); !enabled(config.Levels[slog.LevelError].AutoCallStack) {
+		t.Errorf("Expected ConfigureFromFile to load the file, got %+v", config)
+	}
+}
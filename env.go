@@ -0,0 +1,140 @@
+package zlog
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const defaultEnvPrefix = "ZLOG"
+
+// ConfigureFromEnv reads the configuration from environment variables named prefix_SETTING
+// (an empty prefix means "ZLOG"):
+//
+//	ZLOG_LEVEL=info                          minimum level
+//	ZLOG_FORMAT=text                         json or text
+//	ZLOG_OUTPUT=stderr                       stdout, stderr or a file path
+//	ZLOG_<LEVEL>_AUTOSOURCE=true             per level, e.g. ZLOG_ERROR_AUTOSOURCE
+//	ZLOG_<LEVEL>_AUTOCALLSTACK=true          per level, e.g. ZLOG_ERROR_AUTOCALLSTACK
+//	ZLOG_<LEVEL>_MAXCALLSTACKDEPTH=20        per level, e.g. ZLOG_DEBUG_MAXCALLSTACKDEPTH
+//	ZLOG_<LEVEL>_FRAMEFORMAT=structured      per level, e.g. ZLOG_ERROR_FRAMEFORMAT
+//	ZLOG_<LEVEL>_PATHMODE=module             per level, e.g. ZLOG_ERROR_PATHMODE
+//	ZLOG_<LEVEL>_AUTOGOROUTINEID=true        per level, e.g. ZLOG_INFO_AUTOGOROUTINEID
+//	ZLOG_<LEVEL>_AUTOPPROFLABELS=true        per level, e.g. ZLOG_INFO_AUTOPPROFLABELS
+//
+// The variables are read when the Configurable is applied and, like every Configurable,
+// override what the ones before it set, field by field. Empty variables are ignored;
+// invalid values and unknown settings are reported as warnings and ignored.
+//
+// Example:
+//
+//	// Precedence: defaults < log-config.json < environment
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.UseConfig(defaults.Merge(zlog.ConfigureFromJSONFile("log-config.json"))),
+//	    zlog.ConfigureFromEnv("ZLOG"),
+//	))
+func ConfigureFromEnv(prefix string) Configurable {
+	prefix = strings.TrimSuffix(prefix, "_")
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+	return func(config *Config) {
+		for _, variable := range os.Environ() {
+			name, value, _ := strings.Cut(variable, "=")
+			setting, ok := strings.CutPrefix(name, prefix+"_")
+			if !ok || value == "" {
+				continue
+			}
+			if err := applyEnvSetting(config, strings.ToUpper(setting), value); err != nil {
+				Warn().Segment("zlog", "env").KeyValue("variable", name).Err(err).Msg("Ignoring invalid zlog environment variable")
+			}
+		}
+	}
+}
+
+// applyEnvSetting applies a single variable, setting is the variable name without the prefix
+func applyEnvSetting(config *Config, setting, value string) error {
+	switch setting {
+	case "LEVEL":
+		minLevel, err := parseLevel(value)
+		if err != nil {
+			return err
+		}
+		config.MinLevel = &minLevel
+		return nil
+	case "FORMAT":
+		format, err := parseFormat(value)
+		if err != nil {
+			return err
+		}
+		config.Format = format
+		return nil
+	case "OUTPUT":
+		config.Output = value
+		return nil
+	}
+
+	levelName, field, ok := cutLast(setting, "_")
+	if !ok {
+		return fmt.Errorf("zlog: unknown setting %q", setting)
+	}
+	level, err := parseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	switch field {
+	case "AUTOSOURCE", "AUTOCALLSTACK", "AUTOGOROUTINEID", "AUTOPPROFLABELS":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("zlog: %s must be a boolean, got %q", setting, value)
+		}
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			switch field {
+			case "AUTOSOURCE":
+				levelConf.AutoSource = &enabled
+			case "AUTOCALLSTACK":
+				levelConf.AutoCallStack = &enabled
+			case "AUTOGOROUTINEID":
+				levelConf.AutoGoroutineID = &enabled
+			default:
+				levelConf.AutoPprofLabels = &enabled
+			}
+		})
+	case "MAXCALLSTACKDEPTH":
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return fmt.Errorf("zlog: %s must be a non-negative integer, got %q", setting, value)
+		}
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.MaxCallStackDepth = depth
+		})
+	case "FRAMEFORMAT":
+		format, err := parseFrameFormat(value)
+		if err != nil {
+			return err
+		}
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.FrameFormat = format
+		})
+	case "PATHMODE":
+		mode, err := parsePathMode(value)
+		if err != nil {
+			return err
+		}
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.PathMode = mode
+		})
+	default:
+		return fmt.Errorf("zlog: unknown setting %q (expected AUTOSOURCE, AUTOCALLSTACK, MAXCALLSTACKDEPTH, FRAMEFORMAT, PATHMODE, AUTOGOROUTINEID or AUTOPPROFLABELS)", setting)
+	}
+	return nil
+}
+
+// cutLast slices s around the last instance of sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,154 @@
+package zlog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestConfigureFromEnv tests reading every supported variable
+func TestConfigureFromEnv(t *testing.T) {
+	t.Setenv("APP_LOG_LEVEL", "notice")
+	t.Setenv("APP_LOG_FORMAT", "TEXT")
+	t.Setenv("APP_LOG_OUTPUT", "stderr")
+	t.Setenv("APP_LOG_ERROR_AUTOSOURCE", "true")
+	t.Setenv("APP_LOG_ERROR_AUTOCALLSTACK", "1")
+	t.Setenv("APP_LOG_DEBUG_MAXCALLSTACKDEPTH", "30")
+	t.Setenv("APP_LOG_ERROR_FRAMEFORMAT", "structured")
+	t.Setenv("APP_LOG_ERROR_PATHMODE", "module")
+	t.Setenv("APP_LOG_ERROR_AUTOGOROUTINEID", "true")
+	t.Setenv("APP_LOG_WARN_AUTOSOURCE", "") // Empty values are ignored
+
+	config := zlog.Configure(zlog.ConfigureFromEnv("APP_LOG"))
+
+	if config.MinLevel == nil || *config.MinLevel != zlog.LevelNotice {
+		t.Errorf("Expected minLevel notice, got %v", config.MinLevel)
+	}
+	if config.Format != zlog.FormatText || config.Output != "stderr" {
+		t.Errorf("Expected text format to stderr, got %q to %q", config.Format, config.Output)
+	}
+	if levelConf := config.Levels[slog.LevelError]; !enabled(levelConf.AutoSource) || !enabled(levelConf.AutoCallStack) || levelConf.FrameFormat != zlog.FrameStructured || levelConf.PathMode != zlog.PathModule || !enabled(levelConf.AutoGoroutineID) {
+		t.Errorf("Expected error auto features, got %+v", levelConf)
+	}
+	if depth := config.Levels[slog.LevelDebug].MaxCallStackDepth; depth != 30 {
+		t.Errorf("Expected debug depth 30, got %d", depth)
+	}
+	if _, ok := config.Levels[slog.LevelWarn]; ok {
+		t.Error("Expected empty variable to be ignored")
+	}
+}
+
+// TestConfigureFromEnvPrecedence tests that the environment overrides earlier sources field by field
+func TestConfigureFromEnvPrecedence(t *testing.T) {
+	t.Setenv("ZLOG_ERROR_AUTOCALLSTACK", "false")
+	t.Setenv("ZLOG_LEVEL", "warn")
+
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{"minLevel": "debug", "error": {"autoSource": true, "autoCallStack": true, "maxCallStackDepth": 4}}`)
+	fileConfig, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	config := zlog.Configure(
+		zlog.AutoSourceConfig(slog.LevelInfo, true),
+		zlog.UseConfig(fileConfig),
+		zlog.ConfigureFromEnv(""), // Defaults to ZLOG
+		zlog.MaxCallStackDepthConfig(slog.LevelError, 6),
+	)
+
+	if *config.MinLevel != slog.LevelWarn {
+		t.Errorf("Expected environment minLevel warn, got %v", *config.MinLevel)
+	}
+	if levelConf := config.Levels[slog.LevelError]; !enabled(levelConf.AutoSource) || levelConf.AutoCallStack == nil || *levelConf.AutoCallStack || levelConf.MaxCallStackDepth != 6 {
+		t.Errorf("Expected autoSource from the file, autoCallStack off from the environment and depth 6, got %+v", levelConf)
+	}
+	if !enabled(config.Levels[slog.LevelInfo].AutoSource) {
+		t.Error("Expected option before the file to be kept")
+	}
+}
+
+// TestConfigureFromEnvInvalid tests that invalid variables are reported and ignored
+func TestConfigureFromEnvInvalid(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetOutputWriter(os.Stdout)
+
+	t.Setenv("ZLOG_LEVEL", "verbose")
+	t.Setenv("ZLOG_FORMAT", "xml")
+	t.Setenv("ZLOG_ERROR_AUTOSOURCE", "maybe")
+	t.Setenv("ZLOG_DEBUG_MAXCALLSTACKDEPTH", "-1")
+	t.Setenv("ZLOG_ERROR_COLOR", "true")
+	t.Setenv("ZLOG_EROR_AUTOSOURCE", "true")
+
+	config := zlog.Configure(zlog.ConfigureFromEnv("ZLOG"))
+	if config.MinLevel != nil || config.Format != "" || len(config.Levels) != 0 {
+		t.Errorf("Expected invalid variables to be ignored, got %+v", config)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 warnings, got %d: %s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		logData, err := parseLogOutput(line)
+		if err != nil {
+			t.Fatalf("Failed to parse warning: %v", err)
+		}
+		if logData["level"] != "WARN" || logData["segment"] != "zlog/env" || logData["variable"] == nil {
+			t.Errorf("Unexpected warning: %v", logData)
+		}
+	}
+}
+
+// TestFormatAndOutputConfig tests applying the format and output settings
+func TestFormatAndOutputConfig(t *testing.T) {
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		zlog.SetConfig(zlog.Configure())
+	})
+
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	zlog.SetConfig(zlog.Configure(
+		zlog.FormatConfig(zlog.FormatText),
+		zlog.OutputConfig(logPath),
+	))
+	zlog.Info().KeyValue("key", "value").Message("to file")
+
+	// Reapplying the same output keeps the file open, a writer replaces it
+	zlog.SetConfig(zlog.Configure(zlog.OutputConfig(logPath)))
+	zlog.Info().Message("json line")
+	var buf bytes.Buffer
+	zlog.SetOutputWriter(&buf)
+	zlog.Info().Message("to buffer")
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines in file, got: %s", data)
+	}
+	if !strings.Contains(lines[0], "level=INFO") || !strings.Contains(lines[0], "key=value") || !strings.Contains(lines[0], `msg="to file"`) {
+		t.Errorf("Expected text format, got: %s", lines[0])
+	}
+	if _, err = parseLogOutput(lines[1]); err != nil {
+		t.Errorf("Expected JSON format after reset, got: %s", lines[1])
+	}
+	if !strings.Contains(buf.String(), "to buffer") {
+		t.Errorf("Expected SetOutputWriter to replace the file, got: %s", buf.String())
+	}
+
+	// A file that cannot be opened keeps the current output
+	buf.Reset()
+	zlog.SetConfig(zlog.Configure(zlog.OutputConfig(filepath.Join(logPath, "not-a-dir", "app.log"))))
+	if !strings.Contains(buf.String(), "keeping current output") {
+		t.Errorf("Expected warning on the current output, got: %s", buf.String())
+	}
+}
@@ -0,0 +1,372 @@
+package zlog
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FrameFormat selects how the source and call stack frames of a level are written.
+type FrameFormat string
+
+const (
+	FrameString     FrameFormat = "string"     // "#pkg.Func @ /path/file.go:42", the default
+	FrameStructured FrameFormat = "structured" // {"function":"...","package":"...","file":"...","line":42}, see Frame
+)
+
+// UnmarshalText validates a frame format name, "" selects the default.
+func (f *FrameFormat) UnmarshalText(text []byte) error {
+	format, err := parseFrameFormat(string(text))
+	if err != nil {
+		return err
+	}
+	*f = format
+	return nil
+}
+
+// parseFrameFormat validates a frame format name
+func parseFrameFormat(name string) (FrameFormat, error) {
+	switch format := FrameFormat(strings.ToLower(strings.TrimSpace(name))); format {
+	case "", FrameString, FrameStructured:
+		return format, nil
+	default:
+		return "", fmt.Errorf("zlog: unknown frame format %q (expected string or structured)", name)
+	}
+}
+
+// FrameFormatConfig sets how the source and call stack of level are written.
+// FrameStructured writes each frame as an object shaped like slog.Source with its package added,
+// so log tools do not have to parse "#pkg.Func @ /path/file.go:42" strings.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.AutoCallStackConfig(slog.LevelError, true),
+//	    zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+//	))
+//	zlog.Error().WithSource().Message("Payment failed")
+//	// Output: {..."source":{"function":"github.com/acme/shop/payment.Charge","package":"github.com/acme/shop/payment","file":"/app/payment/charge.go","line":42},"callstack":[{...}]}
+func FrameFormatConfig(level slog.Level, format FrameFormat) Configurable {
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.FrameFormat = format
+		})
+	}
+}
+
+// Frame is a resolved stack frame, as written in the FrameStructured format.
+// Its JSON form matches slog.Source with the package added.
+type Frame struct {
+	Function string `json:"function"` // Fully qualified function, e.g. "github.com/acme/shop/order.(*Service).Place"
+	Package  string `json:"package"`  // Import path of the function, e.g. "github.com/acme/shop/order"
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// LogValue writes the frame as a group, so the text format shows its fields as well
+func (f Frame) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("function", f.Function),
+		slog.String("package", f.Package),
+		slog.String("file", f.File),
+		slog.Int("line", f.Line),
+	)
+}
+
+// FrameFilter removes frames from call stacks before the depth limit is applied, so the frames written
+// are those of your own code rather than of the runtime or the libraries calling it.
+type FrameFilter struct {
+	DropRuntime      bool     `json:"dropRuntime,omitempty"`      // Drop frames of runtime and its subpackages
+	DropStdlib       bool     `json:"dropStdlib,omitempty"`       // Drop frames of the standard library (runtime included)
+	DropPackages     []string `json:"dropPackages,omitempty"`     // Drop frames of packages matching these patterns, see PackageLevelConfig
+	CollapsePackages bool     `json:"collapsePackages,omitempty"` // Keep only the first of consecutive frames from the same package
+}
+
+// DropRuntimeFramesConfig removes the frames of the runtime package and its subpackages from call stacks.
+func DropRuntimeFramesConfig() Configurable {
+	return func(config *Config) {
+		config.updateFrameFilter(func(filter *FrameFilter) {
+			filter.DropRuntime = true
+		})
+	}
+}
+
+// DropStdlibFramesConfig removes the frames of standard library packages, such as net/http, from call stacks.
+func DropStdlibFramesConfig() Configurable {
+	return func(config *Config) {
+		config.updateFrameFilter(func(filter *FrameFilter) {
+			filter.DropStdlib = true
+		})
+	}
+}
+
+// DropFramePackagesConfig removes the frames of packages matching the given patterns from call stacks.
+// Patterns follow PackageLevelConfig: "github.com/acme/mux" or "github.com/acme/mux/...".
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.AutoCallStackConfig(slog.LevelError, true),
+//	    zlog.DropStdlibFramesConfig(),
+//	    zlog.DropFramePackagesConfig("github.com/go-chi/chi/v5/..."),
+//	))
+func DropFramePackagesConfig(patterns ...string) Configurable {
+	return func(config *Config) {
+		config.updateFrameFilter(func(filter *FrameFilter) {
+			filter.DropPackages = append(filter.DropPackages, patterns...)
+		})
+	}
+}
+
+// CollapseFramesConfig keeps only the first of consecutive call stack frames from the same package,
+// e.g. the middleware chain of a router.
+func CollapseFramesConfig() Configurable {
+	return func(config *Config) {
+		config.updateFrameFilter(func(filter *FrameFilter) {
+			filter.CollapsePackages = true
+		})
+	}
+}
+
+// updateFrameFilter applies update to the frame filter, creating it if needed
+func (c *Config) updateFrameFilter(update func(filter *FrameFilter)) {
+	filter := FrameFilter{}
+	if c.FrameFilter != nil {
+		filter = c.FrameFilter.clone()
+	}
+	update(&filter)
+	c.FrameFilter = &filter
+}
+
+func (f FrameFilter) clone() FrameFilter {
+	f.DropPackages = append([]string(nil), f.DropPackages...)
+	return f
+}
+
+const (
+	filteredCallStackFactor = 4   // Frames captured per written frame when a filter is set
+	maxFilteredCallStack    = 256 // Frames captured at most when a filter is set
+)
+
+// frameFilter is a FrameFilter with its package patterns compiled
+type frameFilter struct {
+	FrameFilter
+	packages []packageRule
+}
+
+// newFrameFilter compiles filter, nil if it drops nothing
+func newFrameFilter(filter *FrameFilter) *frameFilter {
+	if filter == nil || (!filter.DropRuntime && !filter.DropStdlib && len(filter.DropPackages) == 0 && !filter.CollapsePackages) {
+		return nil
+	}
+	patterns := make(map[string]Rule, len(filter.DropPackages))
+	for _, pattern := range filter.DropPackages {
+		patterns[pattern] = Rule{}
+	}
+	return &frameFilter{FrameFilter: *filter, packages: compilePackageRules(patterns)}
+}
+
+// drops reports whether the frames of pkg are removed
+func (f *frameFilter) drops(pkg string) bool {
+	if pkg == "" {
+		return false
+	}
+	if (f.DropRuntime || f.DropStdlib) && (pkg == "runtime" || strings.HasPrefix(pkg, "runtime/")) {
+		return true
+	}
+	if f.DropStdlib && isStdlibPackage(pkg) {
+		return true
+	}
+	for i := range f.packages {
+		if f.packages[i].matches(pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStdlibPackage reports whether pkg belongs to the standard library, whose import paths have no dot
+// in their first element. The main package is not part of it.
+func isStdlibPackage(pkg string) bool {
+	first, _, _ := strings.Cut(pkg, "/")
+	return pkg != "main" && !strings.Contains(first, ".")
+}
+
+// frameStyle is how the frames of a level are written
+type frameStyle struct {
+	format FrameFormat
+	paths  PathMode // "" for PathAbsolute
+}
+
+// frameStyle returns the frame style of the level
+func (c LevelConfig) frameStyle() frameStyle {
+	style := frameStyle{format: c.FrameFormat, paths: c.PathMode}
+	if style.paths == PathAbsolute {
+		style.paths = ""
+	}
+	return style
+}
+
+// frameKey identifies a resolved frame in a path mode
+type frameKey struct {
+	pc    uintptr
+	paths PathMode
+}
+
+var (
+	frameStrings sync.Map // frameKey -> string, see formatFrame
+	frames       sync.Map // frameKey -> Frame, see resolveFrame
+)
+
+// sourceFrame is the program counter of a source, formatted only when the entry is written
+type sourceFrame struct {
+	pc    uintptr
+	style frameStyle
+}
+
+func (s sourceFrame) LogValue() slog.Value {
+	if s.style.format == FrameStructured {
+		return resolveFrame(s.pc, s.style.paths).LogValue()
+	}
+	return slog.StringValue(formatFrame(s.pc, s.style.paths))
+}
+
+// callStack is a captured call stack, resolved and filtered only when the entry is written
+type callStack struct {
+	pcs    []uintptr
+	depth  int          // Frames written, after filtering
+	filter *frameFilter // nil = keep every frame
+	style  frameStyle
+}
+
+func (s callStack) LogValue() slog.Value {
+	var formatted []string
+	var structured []Frame
+	if s.style.format == FrameStructured {
+		structured = make([]Frame, 0, min(len(s.pcs), s.depth))
+	} else {
+		formatted = make([]string, 0, min(len(s.pcs), s.depth))
+	}
+	written, previousPackage := 0, ""
+	for _, pc := range s.pcs {
+		if written == s.depth {
+			break
+		}
+		frame := resolveFrame(pc, s.style.paths)
+		if s.filter != nil {
+			if s.filter.drops(frame.Package) {
+				continue
+			}
+			collapsed := s.filter.CollapsePackages && frame.Package == previousPackage
+			previousPackage = frame.Package
+			if collapsed {
+				continue
+			}
+		}
+		if s.style.format == FrameStructured {
+			structured = append(structured, frame)
+		} else {
+			formatted = append(formatted, formatFrame(pc, s.style.paths))
+		}
+		written++
+		if frame.Function == "main.main" {
+			break
+		}
+	}
+	if s.style.format == FrameStructured {
+		return slog.AnyValue(structured)
+	}
+	return slog.AnyValue(formatted)
+}
+
+// sourceAttr returns the "source" attribute of pc in the given frame style
+func sourceAttr(pc uintptr, style frameStyle) slog.Attr {
+	return slog.Any("source", sourceFrame{pc: pc, style: style})
+}
+
+// callStackAttr returns the "callstack" attribute writing up to depth frames of pcs
+func callStackAttr(pcs []uintptr, depth int, filter *frameFilter, style frameStyle) slog.Attr {
+	return slog.Any("callstack", callStack{pcs: pcs, depth: depth, filter: filter, style: style})
+}
+
+// callerPC returns the program counter skip levels up, 0 being the caller of callerPC like runtime.Caller
+func callerPC(skip int) (uintptr, bool) {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) == 0 {
+		return 0, false
+	}
+	return pcs[0], true
+}
+
+// captureCallStack returns the program counters of up to depth frames starting skip levels up, with the
+// same skip as callerPC. With a filter, more frames are captured so that depth frames remain once filtered.
+// The stack is captured with a single runtime.Callers call.
+func captureCallStack(skip, depth int, filter *frameFilter) []uintptr {
+	if depth <= 0 {
+		return []uintptr{}
+	}
+	count := depth
+	if filter != nil {
+		count = min(depth*filteredCallStackFactor, maxFilteredCallStack)
+	}
+	var buf [32]uintptr
+	pcs := buf[:]
+	if count > len(buf) {
+		pcs = make([]uintptr, count)
+	}
+	n := runtime.Callers(skip+1, pcs[:count])
+	return append([]uintptr(nil), pcs[:n]...)
+}
+
+// resolveFrame returns the frame of a program counter returned by runtime.Callers, with its file in the given
+// path mode. runtime.Callers returns one program counter per frame, inlined calls included, so the first frame
+// it resolves to is the one of the program counter.
+func resolveFrame(pc uintptr, paths PathMode) Frame {
+	key := frameKey{pc: pc, paths: paths}
+	if cached, ok := frames.Load(key); ok {
+		return cached.(Frame)
+	}
+	var resolved Frame
+	if paths == "" {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		resolved = Frame{
+			Function: frame.Function,
+			Package:  packageOf(frame.Function),
+			File:     frame.File,
+			Line:     frame.Line,
+		}
+	} else {
+		resolved = resolveFrame(pc, "")
+		resolved.File = trimPath(resolved, paths)
+	}
+	frames.Store(key, resolved)
+	return resolved
+}
+
+// formatFrame returns "#package.Function @ /path/file.go:42" for a program counter returned by runtime.Callers,
+// with the file in the given path mode.
+func formatFrame(pc uintptr, paths PathMode) string {
+	key := frameKey{pc: pc, paths: paths}
+	if cached, ok := frameStrings.Load(key); ok {
+		return cached.(string)
+	}
+	frame := resolveFrame(pc, paths)
+	funcName := frame.Function
+	if funcName == "" {
+		funcName = "?"
+	} else if moduleSeparator := strings.LastIndex(funcName, "/"); moduleSeparator != -1 {
+		funcName = funcName[moduleSeparator+1:]
+	}
+	var b strings.Builder
+	b.WriteByte('#')
+	b.WriteString(funcName)
+	b.WriteString(" @ ")
+	b.WriteString(frame.File)
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatInt(int64(frame.Line), 10))
+	formatted, _ := frameStrings.LoadOrStore(key, b.String())
+	return formatted.(string)
+}
@@ -0,0 +1,209 @@
+package zlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestFrameFormatStructured tests that source and call stack frames are written as objects
+func TestFrameFormatStructured(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.AutoCallStackConfig(slog.LevelError, true),
+	))
+	zlog.Error().WithSource().Message("structured")
+
+	var entry struct {
+		Source    zlog.Frame   `json:"source"`
+		CallStack []zlog.Frame `json:"callstack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+
+	expected := zlog.Frame{
+		Function: "github.com/GokselKUCUKSAHIN/zlog_test.TestFrameFormatStructured",
+		Package:  "github.com/GokselKUCUKSAHIN/zlog_test",
+	}
+	if entry.Source.Function != expected.Function || entry.Source.Package != expected.Package {
+		t.Errorf("Expected source %+v, got %+v", expected, entry.Source)
+	}
+	if !strings.HasSuffix(entry.Source.File, "frames_test.go") || entry.Source.Line == 0 {
+		t.Errorf("Expected source to point to this file, got %s:%d", entry.Source.File, entry.Source.Line)
+	}
+	if len(entry.CallStack) == 0 || entry.CallStack[0] != entry.Source {
+		t.Errorf("Expected call stack to start at the source, got %+v", entry.CallStack)
+	}
+
+	// Other levels keep the string format
+	buf.Reset()
+	zlog.Info().WithSource().Message("default")
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if source, ok := logData["source"].(string); !ok || !strings.Contains(source, "TestFrameFormatStructured @ ") {
+		t.Errorf("Expected string source for info, got %v", logData["source"])
+	}
+}
+
+// TestFrameFormatStructuredText tests that structured frames show their fields in the text format
+func TestFrameFormatStructuredText(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.FormatConfig(zlog.FormatText),
+		zlog.FrameFormatConfig(slog.LevelInfo, zlog.FrameStructured),
+	))
+	zlog.Info().WithSource().Message("text")
+
+	for _, field := range []string{"source.function=", "source.package=github.com/GokselKUCUKSAHIN/zlog_test", "source.file=", "source.line="} {
+		if !strings.Contains(buf.String(), field) {
+			t.Errorf("Expected %q in %q", field, buf.String())
+		}
+	}
+}
+
+// TestFrameFormatJSON tests decoding frame formats from JSON configuration
+func TestFrameFormatJSON(t *testing.T) {
+	var config zlog.Config
+	if err := json.Unmarshal([]byte(`{"error": {"frameFormat": "Structured"}}`), &config); err != nil {
+		t.Fatalf("Failed to decode config: %v", err)
+	}
+	if format := config.Levels[slog.LevelError].FrameFormat; format != zlog.FrameStructured {
+		t.Errorf("Expected structured frame format, got %q", format)
+	}
+
+	if err := json.Unmarshal([]byte(`{"error": {"frameFormat": "xml"}}`), &config); err == nil {
+		t.Error("Expected an error for an unknown frame format")
+	}
+}
+
+// logStructuredCallStack logs an error with its call stack and returns the frames written
+func logStructuredCallStack(t *testing.T, buf *bytes.Buffer, log func()) []zlog.Frame {
+	t.Helper()
+	buf.Reset()
+	log()
+	var entry struct {
+		CallStack []zlog.Frame `json:"callstack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	return entry.CallStack
+}
+
+// framePackages returns the packages of frames
+func framePackages(frames []zlog.Frame) []string {
+	packages := make([]string, 0, len(frames))
+	for _, frame := range frames {
+		packages = append(packages, frame.Package)
+	}
+	return packages
+}
+
+// TestFrameFilterStdlib tests that runtime and standard library frames are removed before the depth limit
+func TestFrameFilterStdlib(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		zlog.Error().WithCallStack().Message("in handler")
+	})
+	logFromHandler := func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	zlog.SetConfig(zlog.Configure(zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured)))
+	unfiltered := framePackages(logStructuredCallStack(t, &buf, logFromHandler))
+	if !strings.Contains(strings.Join(unfiltered, ","), "net/http") {
+		t.Fatalf("Expected net/http frames without a filter, got %v", unfiltered)
+	}
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.DropStdlibFramesConfig(),
+	))
+	filtered := framePackages(logStructuredCallStack(t, &buf, logFromHandler))
+	if len(filtered) < 3 {
+		t.Fatalf("Expected the handler, the helper and the test function, got %v", filtered)
+	}
+	for _, pkg := range filtered {
+		if pkg != "github.com/GokselKUCUKSAHIN/zlog_test" {
+			t.Errorf("Expected only frames of the test package, got %v", filtered)
+			break
+		}
+	}
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.DropRuntimeFramesConfig(),
+		zlog.DropFramePackagesConfig("net/..."),
+	))
+	packages := strings.Join(framePackages(logStructuredCallStack(t, &buf, logFromHandler)), ",")
+	if strings.Contains(packages, "net/http") || strings.Contains(packages, "runtime") {
+		t.Errorf("Expected net/http and runtime frames to be dropped, got %s", packages)
+	}
+	if !strings.Contains(packages, "testing") {
+		t.Errorf("Expected other standard library frames to be kept, got %s", packages)
+	}
+}
+
+// recurse calls log from depth nested calls
+func recurse(depth int, log func()) {
+	if depth == 0 {
+		log()
+		return
+	}
+	recurse(depth-1, log)
+}
+
+// TestFrameFilterCollapse tests that consecutive frames of a package are collapsed into the first one
+// and that the depth applies to the frames written
+func TestFrameFilterCollapse(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	logDeep := func() {
+		recurse(3, func() { zlog.Error().WithCallStack().Message("deep") })
+	}
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.MaxCallStackDepthConfig(slog.LevelError, 5),
+		zlog.DropStdlibFramesConfig(),
+	))
+	if frames := logStructuredCallStack(t, &buf, logDeep); len(frames) != 3 {
+		t.Errorf("Expected the depth to limit the written frames to 3, got %d", len(frames))
+	}
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+		zlog.MaxCallStackDepthConfig(slog.LevelError, 5),
+		zlog.CollapseFramesConfig(),
+	))
+	packages := framePackages(logStructuredCallStack(t, &buf, logDeep))
+	for i := 1; i < len(packages); i++ {
+		if packages[i] == packages[i-1] {
+			t.Fatalf("Expected consecutive frames of a package to be collapsed, got %v", packages)
+		}
+	}
+	if len(packages) < 2 || packages[0] != "github.com/GokselKUCUKSAHIN/zlog_test" || packages[1] != "testing" {
+		t.Errorf("Expected the test package followed by testing, got %v", packages)
+	}
+}
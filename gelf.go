@@ -0,0 +1,305 @@
+package zlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gelfVersion          = "1.1"
+	gelfChunkHeaderSize  = 12
+	gelfMaxChunks        = 128
+	defaultGELFChunkSize = 1420 // Safe for WAN links, raise to 8154 inside a LAN
+)
+
+var gelfChunkMagic = [2]byte{0x1e, 0x0f}
+
+// GELFCompressionType selects how UDP messages are compressed
+type GELFCompressionType int
+
+const (
+	GELFCompressionGzip GELFCompressionType = iota
+	GELFCompressionZlib
+	GELFCompressionNone
+)
+
+// gelfOptions holds configuration for a GELFWriter
+type gelfOptions struct {
+	host        string              // Value of the "host" field (default: os.Hostname)
+	compression GELFCompressionType // UDP only, TCP messages are never compressed
+	chunkSize   int                 // UDP datagram size including the chunk header
+}
+
+type GELFOption = func(options *gelfOptions)
+
+// GELFHost overrides the "host" field sent with every message.
+func GELFHost(host string) GELFOption {
+	return func(options *gelfOptions) {
+		options.host = host
+	}
+}
+
+// GELFCompression selects the compression used for UDP messages (default: gzip).
+func GELFCompression(compression GELFCompressionType) GELFOption {
+	return func(options *gelfOptions) {
+		options.compression = compression
+	}
+}
+
+// GELFChunkSize sets the maximum UDP datagram size. Larger messages are chunked.
+func GELFChunkSize(size int) GELFOption {
+	return func(options *gelfOptions) {
+		options.chunkSize = size
+	}
+}
+
+// GELFWriter is an io.Writer that converts zlog JSON entries to GELF 1.1
+// and ships them to Graylog over UDP or TCP.
+type GELFWriter struct {
+	mu      sync.Mutex
+	network string
+	address string
+	options gelfOptions
+	conn    net.Conn
+}
+
+// NewGELFWriter creates a writer sending GELF messages to address.
+// network must be "udp" (chunked and compressed) or "tcp" (null-byte framed).
+//
+// Levels are mapped to syslog severities, msg becomes short_message,
+// the call stack becomes full_message and every other attribute is sent
+// as an additional field prefixed with an underscore.
+//
+// Example:
+//
+//	writer, err := zlog.NewGELFWriter("udp", "graylog:12201")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer writer.Close()
+//	zlog.SetOutputWriter(writer)
+func NewGELFWriter(network, address string, options ...GELFOption) (*GELFWriter, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("zlog: unsupported GELF network %q", network)
+	}
+	opts := gelfOptions{
+		compression: GELFCompressionGzip,
+		chunkSize:   defaultGELFChunkSize,
+	}
+	opts.host, _ = os.Hostname()
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.chunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("zlog: GELF chunk size %d is too small", opts.chunkSize)
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &GELFWriter{
+		network: network,
+		address: address,
+		options: opts,
+		conn:    conn,
+	}, nil
+}
+
+// Write converts every JSON line in p to a GELF message and sends it.
+// If one fails, the returned count covers the lines sent before it.
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return decodeEntries(p, func(entry logEntry) error {
+		message, err := encodeGELF(entry, w.options.host)
+		if err != nil {
+			return err
+		}
+		if w.network == "tcp" {
+			return w.sendTCP(message)
+		}
+		return w.sendUDP(message)
+	})
+}
+
+func (w *GELFWriter) decodesJSON() {}
+
+// Close closes the underlying connection.
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *GELFWriter) sendTCP(message []byte) error {
+	frame := make([]byte, 0, len(message)+1)
+	frame = append(frame, message...)
+	frame = append(frame, 0)
+
+	if w.conn != nil {
+		if _, err := w.conn.Write(frame); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	// Retry once on a fresh connection, Graylog closes idle TCP inputs.
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	_, err = w.conn.Write(frame)
+	return err
+}
+
+func (w *GELFWriter) sendUDP(message []byte) error {
+	if w.conn == nil {
+		return net.ErrClosed
+	}
+	payload, err := compressGELF(message, w.options.compression)
+	if err != nil {
+		return err
+	}
+	if len(payload) <= w.options.chunkSize {
+		_, err = w.conn.Write(payload)
+		return err
+	}
+
+	dataSize := w.options.chunkSize - gelfChunkHeaderSize
+	count := (len(payload) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return fmt.Errorf("zlog: GELF message needs %d chunks, maximum is %d", count, gelfMaxChunks)
+	}
+	var messageID [8]byte
+	if _, err = rand.Read(messageID[:]); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 0, w.options.chunkSize)
+	for sequence := 0; sequence < count; sequence++ {
+		start := sequence * dataSize
+		end := min(start+dataSize, len(payload))
+		chunk = append(chunk[:0], gelfChunkMagic[:]...)
+		chunk = append(chunk, messageID[:]...)
+		chunk = append(chunk, byte(sequence), byte(count))
+		chunk = append(chunk, payload[start:end]...)
+		if _, err = w.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func compressGELF(message []byte, compression GELFCompressionType) ([]byte, error) {
+	var b bytes.Buffer
+	switch compression {
+	case GELFCompressionNone:
+		return message, nil
+	case GELFCompressionZlib:
+		zw := zlib.NewWriter(&b)
+		if _, err := zw.Write(message); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case GELFCompressionGzip:
+		gw := gzip.NewWriter(&b)
+		if _, err := gw.Write(message); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("zlog: unknown GELF compression")
+	}
+	return b.Bytes(), nil
+}
+
+// encodeGELF converts a decoded zlog entry to a GELF 1.1 JSON document.
+func encodeGELF(entry logEntry, host string) ([]byte, error) {
+	message := map[string]any{
+		"version":       gelfVersion,
+		"host":          host,
+		"short_message": entry.message,
+		"timestamp":     gelfTimestamp(entry.time),
+		"level":         syslogSeverity(entry.level),
+	}
+	if entry.message == "" {
+		message["short_message"] = "-"
+	}
+	if entry.source != nil {
+		message["_function"] = entry.source.Function
+		message["_file"] = entry.source.File
+		if entry.source.Line > 0 {
+			message["_line"] = entry.source.Line
+		}
+	}
+	for key, value := range entry.attrs {
+		if key == "callstack" {
+			message["full_message"] = attrLines(value)
+			continue
+		}
+		addGELFField(message, key, value)
+	}
+	return json.Marshal(message)
+}
+
+// addGELFField adds an additional field. GELF only allows strings and numbers,
+// so nested objects are flattened with "_" and everything else is stringified.
+func addGELFField(message map[string]any, key string, value any) {
+	if nested, ok := value.(map[string]any); ok {
+		for nestedKey, nestedValue := range nested {
+			addGELFField(message, key+"_"+nestedKey, nestedValue)
+		}
+		return
+	}
+
+	name := "_" + gelfFieldName(key)
+	if name == "_id" {
+		name = "__id" // "_id" is reserved by Graylog
+	}
+	if number, ok := value.(json.Number); ok {
+		message[name] = number
+		return
+	}
+	message[name] = attrString(value)
+}
+
+// gelfFieldName replaces characters outside of [\w.-] with underscores.
+func gelfFieldName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+func gelfTimestamp(value string) float64 {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t = time.Now()
+	}
+	return float64(t.UnixMilli()) / 1000
+}
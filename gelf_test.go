@@ -0,0 +1,267 @@
+package zlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// listenGELFUDP starts a UDP listener and points zlog at it through a GELF writer
+func listenGELFUDP(t *testing.T, options ...zlog.GELFOption) net.PacketConn {
+	t.Helper()
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	writer, err := zlog.NewGELFWriter("udp", server.LocalAddr().String(), options...)
+	if err != nil {
+		t.Fatalf("Failed to create GELF writer: %v", err)
+	}
+
+	zlog.SetOutputWriter(writer)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		_ = writer.Close()
+		_ = server.Close()
+	})
+	return server
+}
+
+// readGELFPacket reads one datagram, reassembling chunks if necessary
+func readGELFPacket(t *testing.T, server net.PacketConn) []byte {
+	t.Helper()
+	_ = server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65536)
+
+	chunks := make(map[byte][]byte)
+	for {
+		n, _, err := server.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read GELF packet: %v", err)
+		}
+		packet := append([]byte(nil), buf[:n]...)
+		if len(packet) < 2 || packet[0] != 0x1e || packet[1] != 0x0f {
+			return packet
+		}
+
+		count := packet[11]
+		chunks[packet[10]] = packet[12:]
+		if len(chunks) == int(count) {
+			sequences := make([]int, 0, len(chunks))
+			for sequence := range chunks {
+				sequences = append(sequences, int(sequence))
+			}
+			sort.Ints(sequences)
+			var message []byte
+			for _, sequence := range sequences {
+				message = append(message, chunks[byte(sequence)]...)
+			}
+			return message
+		}
+	}
+}
+
+func decodeGELF(t *testing.T, payload []byte, decompress func(io.Reader) (io.Reader, error)) map[string]any {
+	t.Helper()
+	reader, err := decompress(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("Failed to decompress GELF message: %v", err)
+	}
+	var message map[string]any
+	if err = json.NewDecoder(reader).Decode(&message); err != nil {
+		t.Fatalf("Failed to decode GELF message: %v", err)
+	}
+	return message
+}
+
+func gunzip(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+
+func unzlib(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }
+
+func plain(r io.Reader) (io.Reader, error) { return r, nil }
+
+// TestGELFWriterUDP tests GELF field mapping over compressed UDP
+func TestGELFWriterUDP(t *testing.T) {
+	server := listenGELFUDP(t, zlog.GELFHost("test-host"))
+
+	ctx := context.WithValue(context.Background(), "userID", "user-1")
+	zlog.Error().
+		WithSource().
+		Context(ctx, []string{"userID"}).
+		Segment("payment").
+		Err(errors.New("timeout")).
+		KeyValue("id", "42").
+		Alert().
+		Message("Payment failed")
+
+	message := decodeGELF(t, readGELFPacket(t, server), gunzip)
+
+	expected := map[string]any{
+		"version":         "1.1",
+		"host":            "test-host",
+		"short_message":   "Payment failed",
+		"level":           float64(3),
+		"_segment":        "payment",
+		"_error_msg":      "timeout",
+		"_app_ctx_userID": "user-1",
+		"_alert":          "true",
+		"__id":            "42",
+	}
+	for key, value := range expected {
+		if message[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, message[key])
+		}
+	}
+
+	if _, ok := message["timestamp"].(float64); !ok {
+		t.Errorf("Expected numeric timestamp, got %v", message["timestamp"])
+	}
+	if file, _ := message["_file"].(string); !strings.HasSuffix(file, "gelf_test.go") {
+		t.Errorf("Expected _file to point to the test file, got %v", message["_file"])
+	}
+	if _, ok := message["_line"].(float64); !ok {
+		t.Errorf("Expected numeric _line, got %v", message["_line"])
+	}
+}
+
+// TestGELFWriterCompression tests zlib and uncompressed UDP payloads
+func TestGELFWriterCompression(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression zlog.GELFCompressionType
+		decompress  func(io.Reader) (io.Reader, error)
+	}{
+		{"zlib", zlog.GELFCompressionZlib, unzlib},
+		{"none", zlog.GELFCompressionNone, plain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := listenGELFUDP(t, zlog.GELFCompression(tt.compression))
+
+			zlog.Warn().Message("compressed")
+
+			message := decodeGELF(t, readGELFPacket(t, server), tt.decompress)
+			if message["short_message"] != "compressed" {
+				t.Errorf("Expected short_message='compressed', got %v", message["short_message"])
+			}
+			if message["level"] != float64(4) {
+				t.Errorf("Expected level=4, got %v", message["level"])
+			}
+		})
+	}
+}
+
+// TestGELFWriterChunking tests that large messages are split into GELF chunks
+func TestGELFWriterChunking(t *testing.T) {
+	server := listenGELFUDP(t, zlog.GELFCompression(zlog.GELFCompressionNone), zlog.GELFChunkSize(128))
+
+	payload := strings.Repeat("x", 1000)
+	zlog.Info().KeyValue("payload", payload).Message("large")
+
+	message := decodeGELF(t, readGELFPacket(t, server), plain)
+	if message["_payload"] != payload {
+		t.Error("Expected chunked payload to be reassembled intact")
+	}
+}
+
+// TestGELFWriterShortWrite tests that a failing message is reported as a short write after the ones sent
+func TestGELFWriterShortWrite(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on UDP: %v", err)
+	}
+	defer server.Close()
+	writer, err := zlog.NewGELFWriter("udp", server.LocalAddr().String(),
+		zlog.GELFCompression(zlog.GELFCompressionNone), zlog.GELFChunkSize(128))
+	if err != nil {
+		t.Fatalf("Failed to create GELF writer: %v", err)
+	}
+	defer writer.Close()
+
+	first := `{"level":"INFO","msg":"sent"}` + "\n"
+	tooLarge := `{"level":"INFO","msg":"` + strings.Repeat("x", 128*200) + `"}` + "\n"
+	n, err := writer.Write([]byte(first + tooLarge))
+	if err == nil || n != len(first) {
+		t.Errorf("Expected a short write of %d bytes with an error, got %d, %v", len(first), n, err)
+	}
+	if message := decodeGELF(t, readGELFPacket(t, server), plain); message["short_message"] != "sent" {
+		t.Errorf("Expected the first message to be sent, got %v", message)
+	}
+}
+
+// TestGELFWriterTCP tests null-byte framed messages over TCP
+func TestGELFWriterTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen on TCP: %v", err)
+	}
+	defer listener.Close()
+
+	frames := make(chan []byte, 2)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			frame, err := reader.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			frames <- frame[:len(frame)-1]
+		}
+	}()
+
+	writer, err := zlog.NewGELFWriter("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to create GELF writer: %v", err)
+	}
+	zlog.SetOutputWriter(writer)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		_ = writer.Close()
+	})
+
+	zlog.Info().Message("first")
+	zlog.Debug().Message("second")
+
+	for _, expected := range []struct {
+		message string
+		level   float64
+	}{{"first", 6}, {"second", 7}} {
+		select {
+		case frame := <-frames:
+			message := decodeGELF(t, frame, plain)
+			if message["short_message"] != expected.message || message["level"] != expected.level {
+				t.Errorf("Expected %s at level %v, got %v", expected.message, expected.level, message)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for GELF TCP frame")
+		}
+	}
+}
+
+// TestGELFWriterInvalidNetwork tests that unsupported transports are rejected
+func TestGELFWriterInvalidNetwork(t *testing.T) {
+	if _, err := zlog.NewGELFWriter("unix", "/tmp/graylog.sock"); err == nil {
+		t.Error("Expected error for unsupported network")
+	}
+}
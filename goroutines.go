@@ -0,0 +1,248 @@
+package zlog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AutoGoroutineIDConfig adds the ID of the logging goroutine as "goroutine" to entries of level,
+// to tell apart the interleaved entries of concurrent workers.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.AutoGoroutineIDConfig(slog.LevelInfo, true)))
+//	zlog.Info().Message("Batch done")
+//	// Output: {"time":"2024-03-07T10:00:00Z","level":"INFO","msg":"Batch done","goroutine":42}
+func AutoGoroutineIDConfig(level slog.Level, enabled bool) Configurable {
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.AutoGoroutineID = &enabled
+		})
+	}
+}
+
+// AutoPprofLabelsConfig adds the runtime/pprof labels of the entry's context as "pprof_labels" to entries of level.
+// Go only exposes the labels of a goroutine through the context passed to pprof.Do or pprof.WithLabels,
+// so pass that context with Context; entries without a context get no labels.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.AutoPprofLabelsConfig(slog.LevelInfo, true)))
+//	pprof.Do(ctx, pprof.Labels("worker", "3"), func(ctx context.Context) {
+//	    zlog.Info().Context(ctx, nil).Message("Batch done")
+//	    // Output: {"time":"2024-03-07T10:00:00Z","level":"INFO","msg":"Batch done","pprof_labels":{"worker":"3"}}
+//	})
+func AutoPprofLabelsConfig(level slog.Level, enabled bool) Configurable {
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.AutoPprofLabels = &enabled
+		})
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the "goroutine 42 [running]:" header of its stack
+func goroutineID() (uint64, bool) {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	const prefix = "goroutine "
+	if n <= len(prefix) || string(buf[:len(prefix)]) != prefix {
+		return 0, false
+	}
+	var id uint64
+	digits := 0
+	for _, c := range buf[len(prefix):n] {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+		digits++
+	}
+	return id, digits > 0
+}
+
+// pprofLabels returns the pprof labels of ctx sorted by key, nil if there are none
+func pprofLabels(ctx context.Context) []slog.Attr {
+	var labels []slog.Attr
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels = append(labels, slog.String(key, value))
+		return true
+	})
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+	return labels
+}
+
+// GoroutineDump selects the entries that get a dump of every goroutine, see WithAllGoroutines.
+type GoroutineDump struct {
+	OnFatal bool `json:"onFatal,omitempty"` // Entries written by Fatal and Fatalf
+	OnAlert bool `json:"onAlert,omitempty"` // Entries marked with Alert
+}
+
+// GoroutineDumpOnFatalConfig adds a dump of every goroutine to the entries written by Fatal and Fatalf,
+// to find out what a deadlocked or hung process was doing when it gave up.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.GoroutineDumpOnFatalConfig(true)))
+//	zlog.Error().Fatal("Workers did not stop in time")
+func GoroutineDumpOnFatalConfig(enabled bool) Configurable {
+	return func(config *Config) {
+		config.updateGoroutineDump(func(dump *GoroutineDump) {
+			dump.OnFatal = enabled
+		})
+	}
+}
+
+// GoroutineDumpOnAlertConfig adds a dump of every goroutine to the entries marked with Alert.
+func GoroutineDumpOnAlertConfig(enabled bool) Configurable {
+	return func(config *Config) {
+		config.updateGoroutineDump(func(dump *GoroutineDump) {
+			dump.OnAlert = enabled
+		})
+	}
+}
+
+// updateGoroutineDump applies update to the goroutine dump settings, creating them if needed
+func (c *Config) updateGoroutineDump(update func(dump *GoroutineDump)) {
+	dump := GoroutineDump{}
+	if c.GoroutineDump != nil {
+		dump = *c.GoroutineDump
+	}
+	update(&dump)
+	c.GoroutineDump = &dump
+}
+
+// GoroutineGroup is a set of goroutines with identical stacks, as written in the "goroutines" field.
+type GoroutineGroup struct {
+	Count     int      `json:"count"`
+	State     string   `json:"state"` // e.g. "running" or "chan receive", without the time spent waiting
+	IDs       []uint64 `json:"ids"`
+	Stack     []Frame  `json:"stack"`               // Innermost frame first
+	CreatedBy *Frame   `json:"createdBy,omitempty"` // The go statement that started the goroutines
+}
+
+const (
+	goroutineDumpSize    = 64 << 10 // Initial buffer for the dump
+	maxGoroutineDumpSize = 64 << 20 // Larger dumps are cut
+)
+
+// goroutineDump is the text dump of every goroutine, parsed only when the entry is written
+type goroutineDump struct {
+	stacks []byte
+	paths  PathMode
+}
+
+func (d goroutineDump) LogValue() slog.Value {
+	return slog.AnyValue(parseGoroutines(d.stacks, d.paths))
+}
+
+// goroutinesAttr returns the "goroutines" attribute with a dump of every goroutine taken now
+func goroutinesAttr(paths PathMode) slog.Attr {
+	buf := make([]byte, goroutineDumpSize)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxGoroutineDumpSize {
+			return slog.Any("goroutines", goroutineDump{stacks: buf[:n], paths: paths})
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseGoroutines parses the output of runtime.Stack for all goroutines and groups the goroutines
+// with the same state and stack, largest group first
+func parseGoroutines(dump []byte, paths PathMode) []GoroutineGroup {
+	byKey := make(map[string]*GoroutineGroup)
+	var groups []*GoroutineGroup
+	for _, block := range strings.Split(string(dump), "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		id, state, ok := parseGoroutineHeader(lines[0])
+		if !ok {
+			continue
+		}
+
+		var stack []Frame
+		var createdBy *Frame
+		var key strings.Builder
+		key.WriteString(state)
+		for i := 1; i < len(lines); i++ {
+			function := lines[i]
+			if strings.HasPrefix(function, "...") || i+1 == len(lines) {
+				continue // "...additional frames elided..."
+			}
+			i++
+			frame := parseGoroutineFrame(function, lines[i], paths)
+			key.WriteString("\n")
+			key.WriteString(lines[i])
+			if strings.HasPrefix(function, "created by ") {
+				createdBy = &frame
+				continue
+			}
+			stack = append(stack, frame)
+		}
+
+		group, ok := byKey[key.String()]
+		if !ok {
+			group = &GoroutineGroup{State: state, Stack: stack, CreatedBy: createdBy}
+			byKey[key.String()] = group
+			groups = append(groups, group)
+		}
+		group.Count++
+		group.IDs = append(group.IDs, id)
+	}
+
+	result := make([]GoroutineGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.IDs, func(i, j int) bool { return group.IDs[i] < group.IDs[j] })
+		result = append(result, *group)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].IDs[0] < result[j].IDs[0]
+	})
+	return result
+}
+
+// parseGoroutineHeader parses "goroutine 7 [chan receive, 2 minutes]:" into 7 and "chan receive"
+func parseGoroutineHeader(header string) (id uint64, state string, ok bool) {
+	rest, ok := strings.CutPrefix(header, "goroutine ")
+	if !ok {
+		return 0, "", false
+	}
+	idText, state, ok := strings.Cut(rest, " [")
+	if !ok {
+		return 0, "", false
+	}
+	idText, _, _ = strings.Cut(idText, " ") // GOTRACEBACK=system adds "gp=... m=..."
+	if id, err := strconv.ParseUint(idText, 10, 64); err == nil {
+		state, _, _ = strings.Cut(strings.TrimSuffix(state, "]:"), ",")
+		return id, state, true
+	}
+	return 0, "", false
+}
+
+// parseGoroutineFrame parses a function line such as "main.worker(0xc000012345, ...)" or
+// "created by main.start in goroutine 1" and its location line "\t/app/main.go:42 +0x1d"
+func parseGoroutineFrame(function, location string, paths PathMode) Frame {
+	if created, ok := strings.CutPrefix(function, "created by "); ok {
+		function, _, _ = strings.Cut(created, " in goroutine ")
+	} else if args := strings.LastIndexByte(function, '('); args > 0 {
+		function = function[:args]
+	}
+	location, _, _ = strings.Cut(strings.TrimSpace(location), " +0x")
+	frame := Frame{Function: function, Package: packageOf(function), File: location}
+	if separator := strings.LastIndexByte(location, ':'); separator != -1 {
+		if line, err := strconv.Atoi(location[separator+1:]); err == nil {
+			frame.File, frame.Line = location[:separator], line
+		}
+	}
+	if paths != "" {
+		frame.File = trimPath(frame, paths)
+	}
+	return frame
+}
@@ -0,0 +1,195 @@
+package zlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestAutoGoroutineID tests that entries carry the ID of the goroutine that logged them
+func TestAutoGoroutineID(t *testing.T) {
+	var buf syncBuffer
+	zlog.SetOutputWriter(&buf)
+	zlog.SetConfig(zlog.Configure(zlog.AutoGoroutineIDConfig(slog.LevelInfo, true)))
+	defer setupTestLogger(&bytes.Buffer{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			zlog.Info().Message("worker")
+		}()
+	}
+	wg.Wait()
+	zlog.Warn().Message("not enabled for warn")
+
+	ids := make(map[float64]struct{})
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		id, ok := entry["goroutine"].(float64)
+		if entry["msg"] == "not enabled for warn" {
+			if ok {
+				t.Error("Expected no goroutine ID for warn")
+			}
+			continue
+		}
+		if !ok || id <= 0 {
+			t.Fatalf("Expected a goroutine ID, got %v", entry["goroutine"])
+		}
+		ids[id] = struct{}{}
+	}
+	if len(ids) != 4 {
+		t.Errorf("Expected 4 distinct goroutine IDs, got %v", ids)
+	}
+}
+
+// TestAutoPprofLabels tests that entries carry the pprof labels of their context
+func TestAutoPprofLabels(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.AutoPprofLabelsConfig(slog.LevelInfo, true)))
+	pprof.Do(context.Background(), pprof.Labels("worker", "3", "job", "resize"), func(ctx context.Context) {
+		zlog.Info().Context(ctx, nil).Message("labelled")
+	})
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	labels, ok := logData["pprof_labels"].(map[string]any)
+	if !ok || labels["worker"] != "3" || labels["job"] != "resize" {
+		t.Errorf("Expected the labels of the context, got %v", logData["pprof_labels"])
+	}
+
+	// No context, or a context without labels, adds nothing
+	for _, log := range []func(){
+		func() { zlog.Info().Message("no context") },
+		func() { zlog.Info().Context(context.Background(), nil).Message("no labels") },
+	} {
+		buf.Reset()
+		log()
+		if strings.Contains(buf.String(), "pprof_labels") {
+			t.Errorf("Expected no labels, got %s", buf.String())
+		}
+	}
+}
+
+// blockGoroutines starts n goroutines blocked on a channel receive until the returned function is called
+func blockGoroutines(n int) (stop func()) {
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	for i := 0; i < n; i++ {
+		started.Add(1)
+		go func() {
+			started.Done()
+			<-release
+		}()
+	}
+	started.Wait()
+	time.Sleep(10 * time.Millisecond) // Let them reach the receive
+	return func() { close(release) }
+}
+
+// goroutineGroups returns the goroutine groups of a JSON entry
+func goroutineGroups(t *testing.T, line string) []zlog.GoroutineGroup {
+	t.Helper()
+	var entry struct {
+		Goroutines []zlog.GoroutineGroup `json:"goroutines"`
+	}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	return entry.Goroutines
+}
+
+// TestWithAllGoroutines tests that goroutines with the same stack are grouped with their count
+func TestWithAllGoroutines(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	stop := blockGoroutines(5)
+	defer stop()
+
+	zlog.Error().WithAllGoroutines().Message("hung")
+	groups := goroutineGroups(t, buf.String())
+	if len(groups) < 2 {
+		t.Fatalf("Expected the blocked goroutines and the test goroutine, got %+v", groups)
+	}
+
+	var blocked *zlog.GoroutineGroup
+	for i, group := range groups {
+		if group.CreatedBy != nil && strings.HasSuffix(group.CreatedBy.Function, "blockGoroutines") {
+			blocked = &groups[i]
+		}
+	}
+	if blocked == nil {
+		t.Fatalf("Expected a group created by blockGoroutines, got %+v", groups)
+	}
+	if blocked.Count != 5 || len(blocked.IDs) != 5 || blocked.State != "chan receive" {
+		t.Errorf("Expected 5 goroutines waiting on chan receive, got %d %v %q", blocked.Count, blocked.IDs, blocked.State)
+	}
+	if len(blocked.Stack) == 0 || !strings.HasSuffix(blocked.Stack[0].Function, "blockGoroutines.func1") ||
+		blocked.Stack[0].Package != "github.com/GokselKUCUKSAHIN/zlog_test" || blocked.Stack[0].Line == 0 {
+		t.Errorf("Unexpected stack %+v", blocked.Stack)
+	}
+	if groups[0].Count < blocked.Count {
+		t.Errorf("Expected the largest group first, got %+v", groups)
+	}
+}
+
+// TestGoroutineDumpOnAlert tests that the goroutine dump is added to Alert entries only when configured
+func TestGoroutineDumpOnAlert(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.Error().Alert().Message("not configured")
+	if strings.Contains(buf.String(), "goroutines") {
+		t.Errorf("Expected no goroutine dump, got %s", buf.String())
+	}
+
+	zlog.SetConfig(zlog.Configure(zlog.GoroutineDumpOnAlertConfig(true)))
+	buf.Reset()
+	zlog.Error().Message("not an alert")
+	if strings.Contains(buf.String(), "goroutines") {
+		t.Errorf("Expected no goroutine dump without Alert, got %s", buf.String())
+	}
+
+	buf.Reset()
+	zlog.Error().Alert().Message("alert")
+	if groups := goroutineGroups(t, buf.String()); len(groups) == 0 {
+		t.Errorf("Expected a goroutine dump, got %s", buf.String())
+	}
+}
+
+// TestGoroutineDumpOnFatal tests that Fatal writes the goroutine dump before exiting, in a child process
+func TestGoroutineDumpOnFatal(t *testing.T) {
+	if os.Getenv(fatalEnv) == t.Name() {
+		zlog.SetOutputWriter(os.Stdout)
+		zlog.SetConfig(zlog.Configure(zlog.GoroutineDumpOnFatalConfig(true)))
+		blockGoroutines(3)
+		zlog.Error().Fatal("giving up")
+		return
+	}
+
+	line := runFatal(t, t.Name())
+	for _, group := range goroutineGroups(t, line) {
+		if group.CreatedBy != nil && strings.HasSuffix(group.CreatedBy.Function, "blockGoroutines") && group.Count == 3 {
+			return
+		}
+	}
+	t.Errorf("Expected the 3 blocked goroutines in the dump, got %s", line)
+}
@@ -0,0 +1,151 @@
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// entryBuffers holds the buffers entries are measured in, see MaxEntryBytesConfig
+var entryBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// entryHandler redacts, then truncates the records before the handler it wraps writes them.
+// Redacting first ensures a truncation never leaves part of a secret that a pattern would have matched.
+type entryHandler struct {
+	slog.Handler
+	redactor   *redactor
+	limits     SizeLimits
+	newHandler func(w io.Writer) slog.Handler // Renders entries into a buffer when MaxEntryBytes is set
+	output     io.Writer
+	mu         *sync.Mutex // Serializes the writes of measured entries
+}
+
+func (h *entryHandler) Handle(ctx context.Context, record slog.Record) error {
+	t := truncator{limits: h.limits}
+	message := record.Message
+	if h.redactor != nil {
+		message = h.redactor.scrub(message)
+	}
+	message = t.limitString(message, h.limits.MaxMessageLength)
+
+	attrs := make([]slog.Attr, 0, record.NumAttrs()+1)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, h.processAttr(&t, attr))
+		return true
+	})
+	if h.limits.MaxEntryBytes > 0 {
+		return h.handleBounded(ctx, record, message, attrs, &t)
+	}
+	return h.Handler.Handle(ctx, processedRecord(record, message, attrs, t.truncated))
+}
+
+// WithAttrs is not used by the loggers of this package, attributes added this way are neither redacted nor truncated
+func (h *entryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	wrapped := *h
+	wrapped.Handler = h.Handler.WithAttrs(attrs)
+	return &wrapped
+}
+
+// WithGroup is not used by the loggers of this package
+func (h *entryHandler) WithGroup(name string) slog.Handler {
+	wrapped := *h
+	wrapped.Handler = h.Handler.WithGroup(name)
+	return &wrapped
+}
+
+// processAttr redacts and truncates attr, and the members of groups
+func (h *entryHandler) processAttr(t *truncator, attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if attr.Value.Kind() == slog.KindGroup {
+		members := attr.Value.Group()
+		processed := make([]slog.Attr, len(members))
+		for i, member := range members {
+			processed[i] = h.processAttr(t, member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(processed...)}
+	}
+	if h.redactor != nil {
+		attr = h.redactor.attr(attr)
+	}
+	return t.attr(attr)
+}
+
+// handleBounded renders the entry into a buffer and truncates its largest values until it fits in MaxEntryBytes,
+// dropping the last attributes when no value is large enough
+func (h *entryHandler) handleBounded(ctx context.Context, record slog.Record, message string, attrs []slog.Attr, t *truncator) error {
+	buf := entryBuffers.Get().(*bytes.Buffer)
+	defer entryBuffers.Put(buf)
+
+	for {
+		buf.Reset()
+		if err := h.newHandler(buf).Handle(ctx, processedRecord(record, message, attrs, t.truncated)); err != nil {
+			return err
+		}
+		overflow := buf.Len() - h.limits.MaxEntryBytes
+		if overflow <= 0 {
+			break
+		}
+		t.truncated = true
+
+		// The marker takes up to ~30 bytes, values not larger than that are not worth truncating
+		const markerSize = 32
+		index, size := -1, len(message)
+		for i, attr := range attrs {
+			if attrSize := valueSize(attr.Value); attrSize > size {
+				index, size = i, attrSize
+			}
+		}
+		if size <= markerSize {
+			if len(attrs) == 0 {
+				break // Nothing left to shrink
+			}
+			attrs = attrs[:len(attrs)-1]
+			continue
+		}
+		keep := size - overflow - markerSize
+		if keep < 0 {
+			keep = 0
+		}
+		if index < 0 {
+			message = t.cutString(message, keep)
+		} else if attrs[index].Value.Kind() == slog.KindString {
+			attrs[index] = slog.String(attrs[index].Key, t.cutString(attrs[index].Value.String(), keep))
+		} else {
+			attrs[index] = slog.String(attrs[index].Key, fmt.Sprintf("…[truncated %d bytes]", size))
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.output.Write(buf.Bytes())
+	return err
+}
+
+// processedRecord returns a copy of record with the given message and attributes
+func processedRecord(record slog.Record, message string, attrs []slog.Attr, truncated bool) slog.Record {
+	processed := slog.NewRecord(record.Time, record.Level, message, record.PC)
+	processed.AddAttrs(attrs...)
+	if truncated {
+		processed.AddAttrs(slog.Bool("truncated", true))
+	}
+	return processed
+}
+
+// valueSize approximates the rendered size of the strings and structured values of attributes
+func valueSize(value slog.Value) int {
+	switch value.Kind() {
+	case slog.KindString:
+		return len(value.String())
+	case slog.KindAny:
+		data, err := json.Marshal(value.Any())
+		if err != nil {
+			return 0
+		}
+		return len(data)
+	}
+	return 0
+}
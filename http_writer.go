@@ -0,0 +1,769 @@
+package zlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHTTPBatchSize      = 100
+	defaultHTTPBatchBytes     = 1 << 20 // 1 MiB
+	defaultHTTPFlushInterval  = time.Second
+	defaultHTTPMaxRetries     = 3
+	defaultHTTPInitialBackoff = 100 * time.Millisecond
+	defaultHTTPMaxBackoff     = 5 * time.Second
+	defaultHTTPQueueSize      = 16
+	defaultHTTPSpillMaxBytes  = 100 << 20 // 100 MiB
+	defaultHTTPSyncTimeout    = 5 * time.Second
+	httpSpillFilePrefix       = "zlog-spill-"
+)
+
+// HTTPEncoder converts a batch of zlog JSON lines into a request body for a log collector
+type HTTPEncoder interface {
+	ContentType() string
+	Encode(lines [][]byte) ([]byte, error)
+}
+
+// httpOptions holds configuration for an HTTPWriter
+type httpOptions struct {
+	encoder        HTTPEncoder       // Request body format (default: NDJSON)
+	client         *http.Client      // HTTP client used for requests
+	headers        map[string]string // Extra request headers (auth tokens, tenant IDs...)
+	batchSize      int               // Flush after this many entries
+	batchBytes     int               // Flush after this many bytes
+	flushInterval  time.Duration     // Flush pending entries at least this often
+	maxRetries     int               // Retries after the first failed attempt
+	initialBackoff time.Duration     // First retry delay, doubled on every retry
+	maxBackoff     time.Duration     // Upper bound for the retry delay
+	spillDir       string            // Directory for batches that could not be delivered ("" = drop)
+	spillMaxBytes  int64             // Stop spilling when the directory holds this many bytes
+	fallback       io.Writer         // Receives the writer's own errors (default: os.Stderr)
+}
+
+type HTTPOption = func(options *httpOptions)
+
+// HTTPEncoding selects the request body format, see NDJSONEncoder, LokiEncoder and ElasticsearchEncoder.
+func HTTPEncoding(encoder HTTPEncoder) HTTPOption {
+	return func(options *httpOptions) {
+		options.encoder = encoder
+	}
+}
+
+// HTTPClient overrides the http.Client used to send batches.
+func HTTPClient(client *http.Client) HTTPOption {
+	return func(options *httpOptions) {
+		options.client = client
+	}
+}
+
+// HTTPHeader adds a header to every request, e.g. Authorization or X-Scope-OrgID.
+func HTTPHeader(key, value string) HTTPOption {
+	return func(options *httpOptions) {
+		options.headers[key] = value
+	}
+}
+
+// HTTPBatch sets the batch limits: a batch is sent as soon as it holds count entries or size bytes.
+func HTTPBatch(count, size int) HTTPOption {
+	return func(options *httpOptions) {
+		options.batchSize = count
+		options.batchBytes = size
+	}
+}
+
+// HTTPFlushInterval sets how long entries may wait in an incomplete batch.
+func HTTPFlushInterval(interval time.Duration) HTTPOption {
+	return func(options *httpOptions) {
+		options.flushInterval = interval
+	}
+}
+
+// HTTPRetry configures exponential backoff: after a failed request the batch is retried
+// up to maxRetries times, waiting initialBackoff, then twice as long each time up to maxBackoff.
+func HTTPRetry(maxRetries int, initialBackoff, maxBackoff time.Duration) HTTPOption {
+	return func(options *httpOptions) {
+		options.maxRetries = maxRetries
+		options.initialBackoff = initialBackoff
+		options.maxBackoff = maxBackoff
+	}
+}
+
+// HTTPSpillDir enables spilling undeliverable batches to dir.
+// Spilled batches are replayed, oldest first, once the collector is reachable again.
+func HTTPSpillDir(dir string, maxBytes int64) HTTPOption {
+	return func(options *httpOptions) {
+		options.spillDir = dir
+		options.spillMaxBytes = maxBytes
+	}
+}
+
+// HTTPFallback sets where the writer reports its own errors (default: os.Stderr, nil disables).
+func HTTPFallback(writer io.Writer) HTTPOption {
+	return func(options *httpOptions) {
+		options.fallback = writer
+	}
+}
+
+type httpBatch struct {
+	lines [][]byte
+	done  chan struct{} // Closed once the batch has been handled (used by Flush)
+}
+
+// HTTPWriter is an io.Writer that batches zlog entries and ships them to a log collector over HTTP.
+type HTTPWriter struct {
+	url     string
+	options httpOptions
+
+	mu           sync.Mutex
+	pending      [][]byte
+	pendingBytes int
+	closed       bool
+	overflow     []httpBatch // Batches that did not fit in queue, newer than every queued batch
+
+	queue           chan httpBatch
+	overflowed      chan struct{} // Wakes the sender to spill the overflow
+	stopped         chan struct{}
+	spillMu         sync.Mutex
+	spilled         int // Batches in the spill directory, so deliveries only look at it when there are some
+	spillID         atomic.Uint64
+	dropped         atomic.Uint64
+	overflowDropped atomic.Uint64 // Entries dropped because the overflow was full, not reported yet
+}
+
+// NewHTTPWriter creates a batching writer posting to url.
+// Batches are sent from a background goroutine, so logging never waits on the network.
+// Call Close on shutdown to flush the remaining entries.
+//
+// Example:
+//
+//	writer, err := zlog.NewHTTPWriter("http://loki:3100/loki/api/v1/push",
+//	    zlog.HTTPEncoding(zlog.LokiEncoder(map[string]string{"app": "orders"})),
+//	    zlog.HTTPSpillDir("/var/spool/orders-logs", 100<<20),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer writer.Close()
+//	zlog.SetOutputWriter(writer)
+func NewHTTPWriter(url string, options ...HTTPOption) (*HTTPWriter, error) {
+	opts := httpOptions{
+		encoder:        NDJSONEncoder(),
+		client:         &http.Client{Timeout: 10 * time.Second},
+		headers:        make(map[string]string),
+		batchSize:      defaultHTTPBatchSize,
+		batchBytes:     defaultHTTPBatchBytes,
+		flushInterval:  defaultHTTPFlushInterval,
+		maxRetries:     defaultHTTPMaxRetries,
+		initialBackoff: defaultHTTPInitialBackoff,
+		maxBackoff:     defaultHTTPMaxBackoff,
+		spillMaxBytes:  defaultHTTPSpillMaxBytes,
+		fallback:       os.Stderr,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.batchSize <= 0 || opts.batchBytes <= 0 || opts.flushInterval <= 0 {
+		return nil, errors.New("zlog: HTTP batch limits and flush interval must be positive")
+	}
+	var spilled []string
+	if opts.spillDir != "" {
+		if err := os.MkdirAll(opts.spillDir, 0o755); err != nil {
+			return nil, err
+		}
+		var err error
+		if spilled, err = spillFiles(opts.spillDir); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &HTTPWriter{
+		url:        url,
+		options:    opts,
+		spilled:    len(spilled), // Left by an earlier run, replayed with the first batch
+		queue:      make(chan httpBatch, defaultHTTPQueueSize),
+		overflowed: make(chan struct{}, 1),
+		stopped:    make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write adds every JSON line in p to the current batch.
+func (w *HTTPWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		w.pending = append(w.pending, append([]byte(nil), line...))
+		w.pendingBytes += len(line) + 1
+		if len(w.pending) >= w.options.batchSize || w.pendingBytes >= w.options.batchBytes {
+			w.enqueue(httpBatch{lines: w.takePending()})
+		}
+	}
+	return len(p), nil
+}
+
+func (w *HTTPWriter) decodesJSON() {}
+
+// Flush sends all pending entries and waits until they have been delivered, spilled or dropped.
+func (w *HTTPWriter) Flush() error {
+	done, err := w.flush()
+	if err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// Sync is Flush waiting at most 5 seconds, called by Fatal before the program exits.
+func (w *HTTPWriter) Sync() error {
+	done, err := w.flush()
+	if err != nil {
+		return err
+	}
+	timer := time.NewTimer(defaultHTTPSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("zlog: HTTP entries not delivered within %v", defaultHTTPSyncTimeout)
+	}
+}
+
+// flush hands the pending entries to the sender, the returned channel is closed once they are handled
+func (w *HTTPWriter) flush() (<-chan struct{}, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil, os.ErrClosed
+	}
+	done := make(chan struct{})
+	w.enqueue(httpBatch{lines: w.takePending(), done: done})
+	return done, nil
+}
+
+// Close flushes pending entries and stops the background sender.
+func (w *HTTPWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.stopped
+	return nil
+}
+
+// Dropped returns the number of entries the collector rejected or that could be neither delivered nor spilled.
+func (w *HTTPWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+func (w *HTTPWriter) takePending() [][]byte {
+	lines := w.pending
+	w.pending = nil
+	w.pendingBytes = 0
+	return lines
+}
+
+// enqueue hands a batch to the sender without blocking the caller; w.mu must be held.
+// When the sender is backed up or stopping, the batch is added to the overflow, which the sender
+// spills to disk (or drops), and once the overflow is full the entries are dropped, except for Flush batches.
+func (w *HTTPWriter) enqueue(batch httpBatch) {
+	if len(w.overflow) == 0 && !w.closed {
+		select {
+		case w.queue <- batch:
+			return
+		default:
+		}
+	}
+	if len(w.overflow) >= defaultHTTPQueueSize && batch.done == nil {
+		w.dropped.Add(uint64(len(batch.lines)))
+		w.overflowDropped.Add(uint64(len(batch.lines)))
+		return
+	}
+	w.overflow = append(w.overflow, batch)
+	select {
+	case w.overflowed <- struct{}{}:
+	default:
+	}
+}
+
+func (w *HTTPWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.options.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch, ok := <-w.queue:
+			if !ok {
+				// Entries written between the last Flush and Close
+				w.mu.Lock()
+				if len(w.pending) > 0 {
+					w.enqueue(httpBatch{lines: w.takePending()})
+				}
+				w.mu.Unlock()
+				w.spillOverflow(nil)
+				return
+			}
+			w.mu.Lock()
+			overflowing := len(w.overflow) > 0
+			w.mu.Unlock()
+			if overflowing {
+				w.spillOverflow(&batch)
+			} else {
+				w.deliver(batch.lines)
+				if batch.done != nil {
+					close(batch.done)
+				}
+			}
+		case <-w.overflowed:
+			w.spillOverflow(nil)
+		case <-ticker.C:
+			// Pending entries are newer than queued batches, deliver them directly only if none are left
+			w.mu.Lock()
+			idle := len(w.queue) == 0 && len(w.overflow) == 0
+			lines := w.takePending()
+			if !idle && len(lines) > 0 {
+				w.enqueue(httpBatch{lines: lines})
+			}
+			w.mu.Unlock()
+			if idle {
+				w.deliver(lines) // Also replays spilled batches when nothing was logged
+			}
+		}
+	}
+}
+
+// spillOverflow spills the overflow, preceded by first and the batches still queued,
+// which are all older, so that the spilled batches are replayed in order.
+func (w *HTTPWriter) spillOverflow(first *httpBatch) {
+	var batches []httpBatch
+	if first != nil {
+		batches = append(batches, *first)
+	}
+	w.mu.Lock()
+	for queued := true; queued; {
+		select {
+		case batch, ok := <-w.queue:
+			if queued = ok; ok {
+				batches = append(batches, batch)
+			}
+		default:
+			queued = false
+		}
+	}
+	batches = append(batches, w.overflow...)
+	w.overflow = nil
+	w.mu.Unlock()
+
+	if count := w.overflowDropped.Swap(0); count > 0 {
+		reportWriterError(w.options.fallback, "zlog/http", fmt.Errorf("zlog: %d entries did not fit in the send queue", count), "Send queue is full, entries dropped")
+	}
+	var flushed []chan struct{}
+	for _, batch := range batches {
+		if len(batch.lines) > 0 {
+			w.spillOrDrop(batch.lines, errors.New("send queue is full"))
+		}
+		if batch.done != nil {
+			flushed = append(flushed, batch.done)
+		}
+	}
+	if len(flushed) > 0 {
+		w.deliver(nil) // Flush waits for the spilled batches to be replayed if the collector is reachable
+		for _, done := range flushed {
+			close(done)
+		}
+	}
+}
+
+// deliver sends a batch, keeping spilled batches ahead of it so entries arrive in order.
+func (w *HTTPWriter) deliver(lines [][]byte) {
+	if !w.replaySpilled() {
+		if len(lines) > 0 {
+			w.spillOrDrop(lines, errors.New("collector unavailable, earlier batches still spilled"))
+		}
+		return
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if failed, retriable, err := w.send(lines); err != nil {
+		if retriable {
+			w.spillOrDrop(failed, err)
+			return
+		}
+		w.dropped.Add(uint64(len(failed)))
+		reportWriterError(w.options.fallback, "zlog/http", err, "Collector rejected log batch, entries dropped")
+	}
+}
+
+// send posts a batch, retrying with exponential backoff on network errors, 429 and 5xx responses.
+// It returns the lines that were not delivered: the whole batch, or when the collector reports
+// failures per entry (Elasticsearch), only the entries that failed with a retriable status.
+// Entries the collector rejected for good are counted as dropped and reported here.
+func (w *HTTPWriter) send(lines [][]byte) (failed [][]byte, retriable bool, err error) {
+	checker, _ := w.options.encoder.(httpResponseChecker)
+	backoff := w.options.initialBackoff
+	for attempt := 0; ; attempt++ {
+		body, err := w.options.encoder.Encode(lines)
+		if err != nil {
+			return lines, false, err
+		}
+		response, retriable, err := w.post(body, checker != nil)
+		if err == nil && checker != nil {
+			retry, rejected, checkErr := checker.checkResponse(response, lines)
+			if rejected > 0 {
+				w.dropped.Add(uint64(rejected))
+				reportWriterError(w.options.fallback, "zlog/http", checkErr, "Collector rejected log entries, entries dropped")
+			}
+			if len(retry) > 0 {
+				lines, retriable = retry, true
+				err = fmt.Errorf("zlog: collector failed %d entries with a retriable status", len(retry))
+				if rejected == 0 && checkErr != nil {
+					err = checkErr
+				}
+			}
+		}
+		if err == nil {
+			return nil, false, nil
+		}
+		if !retriable || attempt >= w.options.maxRetries {
+			return lines, retriable, err
+		}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, w.options.maxBackoff)
+	}
+}
+
+// post sends a request body, returning the response body if readResponse is set
+func (w *HTTPWriter) post(body []byte, readResponse bool) (response []byte, retriable bool, err error) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	request.Header.Set("Content-Type", w.options.encoder.ContentType())
+	for key, value := range w.options.headers {
+		request.Header.Set(key, value)
+	}
+
+	resp, err := w.options.client.Do(request)
+	if err != nil {
+		return nil, true, err
+	}
+	if readResponse {
+		response, err = io.ReadAll(resp.Body)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("zlog: collector responded %s", resp.Status)
+		return nil, resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	return response, false, nil
+}
+
+func (w *HTTPWriter) spillOrDrop(lines [][]byte, cause error) {
+	if w.options.spillDir == "" {
+		w.dropped.Add(uint64(len(lines)))
+		reportWriterError(w.options.fallback, "zlog/http", cause, "Failed to send log batch, entries dropped")
+		return
+	}
+	if err := w.spill(lines); err != nil {
+		w.dropped.Add(uint64(len(lines)))
+		reportWriterError(w.options.fallback, "zlog/http", err, "Failed to spill log batch, entries dropped")
+	}
+}
+
+// spill writes a batch as an NDJSON file. The file is renamed into place once complete,
+// so a crash never leaves a half-written batch behind for replay.
+func (w *HTTPWriter) spill(lines [][]byte) error {
+	w.spillMu.Lock()
+	defer w.spillMu.Unlock()
+
+	var size int64
+	for _, line := range lines {
+		size += int64(len(line)) + 1
+	}
+	used, err := spillDirSize(w.options.spillDir)
+	if err != nil {
+		return err
+	}
+	if used+size > w.options.spillMaxBytes {
+		return fmt.Errorf("zlog: spill directory limit of %d bytes reached", w.options.spillMaxBytes)
+	}
+
+	name := fmt.Sprintf("%s%020d-%06d.ndjson", httpSpillFilePrefix, time.Now().UnixNano(), w.spillID.Add(1))
+	if err = writeSpillFile(filepath.Join(w.options.spillDir, name), lines); err != nil {
+		return err
+	}
+	w.spilled++
+	return nil
+}
+
+func writeSpillFile(path string, lines [][]byte) error {
+	data := append(bytes.Join(lines, []byte{'\n'}), '\n')
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// replaySpilled sends spilled batches oldest first and reports whether none are left.
+func (w *HTTPWriter) replaySpilled() bool {
+	if w.options.spillDir == "" {
+		return true
+	}
+	w.spillMu.Lock()
+	defer w.spillMu.Unlock()
+	if w.spilled == 0 {
+		return true
+	}
+
+	files, err := spillFiles(w.options.spillDir)
+	if err != nil {
+		reportWriterError(w.options.fallback, "zlog/http", err, "Failed to list spilled log batches")
+		return false
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			reportWriterError(w.options.fallback, "zlog/http", err, "Failed to read spilled log batch")
+			return false
+		}
+		lines := bytes.Split(bytes.TrimSpace(data), []byte{'\n'})
+		failed, retriable, err := w.send(lines)
+		if err != nil && retriable {
+			// Keep only the entries still to be delivered, so the others are not sent twice
+			if len(failed) < len(lines) {
+				if err = writeSpillFile(path, failed); err != nil {
+					reportWriterError(w.options.fallback, "zlog/http", err, "Failed to rewrite spilled log batch")
+				}
+			}
+			return false
+		}
+		if err != nil {
+			w.dropped.Add(uint64(len(failed)))
+			reportWriterError(w.options.fallback, "zlog/http", err, "Collector rejected spilled log batch, entries dropped")
+		}
+		if err = os.Remove(path); err != nil {
+			reportWriterError(w.options.fallback, "zlog/http", err, "Failed to remove replayed log batch")
+			return false
+		}
+	}
+	w.spilled = 0
+	return true
+}
+
+func spillFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, httpSpillFilePrefix) || !strings.HasSuffix(name, ".ndjson") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func spillDirSize(dir string) (int64, error) {
+	files, err := spillFiles(dir)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		size += info.Size()
+	}
+	return size, nil
+}
+
+type ndjsonEncoder struct{}
+
+// NDJSONEncoder sends entries unchanged, one JSON document per line.
+func NDJSONEncoder() HTTPEncoder {
+	return ndjsonEncoder{}
+}
+
+func (ndjsonEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (ndjsonEncoder) Encode(lines [][]byte) ([]byte, error) {
+	return append(bytes.Join(lines, []byte{'\n'}), '\n'), nil
+}
+
+type lokiEncoder struct {
+	labels map[string]string
+}
+
+// LokiEncoder encodes batches for the Loki push API (/loki/api/v1/push).
+// Every stream carries the given static labels plus "level" and, when present, "segment".
+func LokiEncoder(labels map[string]string) HTTPEncoder {
+	return lokiEncoder{labels: labels}
+}
+
+func (lokiEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (e lokiEncoder) Encode(lines [][]byte) ([]byte, error) {
+	type lokiStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	streams := make([]*lokiStream, 0)
+	byLabels := make(map[string]*lokiStream)
+	for _, line := range lines {
+		entry := decodeEntry(line)
+		labels := make(map[string]string, len(e.labels)+2)
+		for key, value := range e.labels {
+			labels[key] = value
+		}
+		labels["level"] = strings.ToLower(entry.levelName)
+		if labels["level"] == "" {
+			labels["level"] = strings.ToLower(entry.level.String())
+		}
+		if segment, ok := entry.attrs["segment"].(string); ok && segment != "" {
+			labels["segment"] = segment
+		}
+
+		// encoding/json sorts map keys, which makes the encoded label set a stable stream key
+		key, err := json.Marshal(labels)
+		if err != nil {
+			return nil, err
+		}
+		stream, ok := byLabels[string(key)]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			byLabels[string(key)] = stream
+			streams = append(streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{lokiTimestamp(entry.time), string(line)})
+	}
+	return json.Marshal(map[string]any{"streams": streams})
+}
+
+func lokiTimestamp(value string) string {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t = time.Now()
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// httpResponseChecker is implemented by encoders whose collector reports failures per entry
+// in a successful response
+type httpResponseChecker interface {
+	// checkResponse returns the lines to send again and the number rejected for good, with their first error,
+	// or why the lines are sent again if none is rejected
+	checkResponse(response []byte, lines [][]byte) (retry [][]byte, rejected int, err error)
+}
+
+type elasticsearchEncoder struct {
+	action []byte
+}
+
+// ElasticsearchEncoder encodes batches for the Elasticsearch _bulk API, indexing every entry into index.
+// The items of the response are checked: entries failing with 429 or a 5xx status are retried,
+// the others failing are dropped and reported.
+func ElasticsearchEncoder(index string) HTTPEncoder {
+	action, _ := json.Marshal(map[string]any{"index": map[string]string{"_index": index}})
+	return elasticsearchEncoder{action: action}
+}
+
+func (elasticsearchEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (e elasticsearchEncoder) Encode(lines [][]byte) ([]byte, error) {
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.Write(e.action)
+		b.WriteByte('\n')
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.Bytes(), nil
+}
+
+// checkResponse reads the items of a _bulk response: an item failing with 429 or a 5xx status
+// is sent again, any other failure is rejected for good. A response that cannot be read is
+// treated like a 5xx, the whole batch is sent again.
+func (elasticsearchEncoder) checkResponse(response []byte, lines [][]byte) ([][]byte, int, error) {
+	var bulk struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(response, &bulk); err != nil {
+		return lines, 0, fmt.Errorf("zlog: invalid _bulk response: %w", err)
+	}
+	if !bulk.Errors {
+		return nil, 0, nil
+	}
+	if len(bulk.Items) != len(lines) {
+		return lines, 0, fmt.Errorf("zlog: _bulk response has %d items for %d entries", len(bulk.Items), len(lines))
+	}
+
+	var retry [][]byte
+	var rejected int
+	var firstErr error
+	for i, item := range bulk.Items {
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, lines[i])
+			default:
+				rejected++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("zlog: _bulk item rejected with status %d: %s", result.Status, result.Error)
+				}
+			}
+		}
+	}
+	return retry, rejected, firstErr
+}
@@ -0,0 +1,519 @@
+package zlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// collector is a fake log collector recording request bodies
+type collector struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	types    []string
+	attempts atomic.Int32
+	status   atomic.Int32
+}
+
+func newCollector(t *testing.T) (*collector, *httptest.Server) {
+	t.Helper()
+	c := &collector{}
+	c.status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.attempts.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		status := int(c.status.Load())
+		if status == http.StatusOK {
+			c.mu.Lock()
+			c.bodies = append(c.bodies, body)
+			c.types = append(c.types, r.Header.Get("Content-Type"))
+			c.mu.Unlock()
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"errors":false}`)) // Read by the Elasticsearch encoder only
+		}
+	}))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+func (c *collector) received() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.bodies...)
+}
+
+// useHTTPWriter creates an HTTP writer and makes it the zlog output
+func useHTTPWriter(t *testing.T, url string, options ...zlog.HTTPOption) *zlog.HTTPWriter {
+	t.Helper()
+	options = append([]zlog.HTTPOption{zlog.HTTPFallback(io.Discard)}, options...)
+	writer, err := zlog.NewHTTPWriter(url, options...)
+	if err != nil {
+		t.Fatalf("Failed to create HTTP writer: %v", err)
+	}
+	zlog.SetOutputWriter(writer)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		_ = writer.Close()
+	})
+	return writer
+}
+
+func ndjsonLines(body []byte) []string {
+	return strings.Split(strings.TrimSpace(string(body)), "\n")
+}
+
+// TestHTTPWriterBatchByCount tests that a full batch is sent without waiting for the interval
+func TestHTTPWriterBatchByCount(t *testing.T) {
+	c, server := newCollector(t)
+	useHTTPWriter(t, server.URL, zlog.HTTPBatch(3, 1<<20), zlog.HTTPFlushInterval(time.Hour))
+
+	zlog.Info().Message("one")
+	zlog.Info().Message("two")
+	zlog.Info().Message("three")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(c.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	bodies := c.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 batch, got %d", len(bodies))
+	}
+	lines := ndjsonLines(bodies[0])
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 entries in batch, got %d", len(lines))
+	}
+	if !strings.Contains(lines[2], `"msg":"three"`) {
+		t.Errorf("Expected entries in order, last entry: %s", lines[2])
+	}
+	if c.types[0] != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %s", c.types[0])
+	}
+}
+
+// TestHTTPWriterTextFormat tests that the collector gets JSON entries with the text format configured
+func TestHTTPWriterTextFormat(t *testing.T) {
+	c, server := newCollector(t)
+	writer := useHTTPWriter(t, server.URL, zlog.HTTPFlushInterval(time.Hour))
+	zlog.SetConfig(zlog.Configure(zlog.FormatConfig(zlog.FormatText)))
+
+	zlog.Info().Message("as json")
+	_ = writer.Flush()
+	bodies := c.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 batch, got %d", len(bodies))
+	}
+	if entry, err := parseLogOutput(string(bodies[0])); err != nil || entry["msg"] != "as json" {
+		t.Errorf("Expected a JSON entry, got %q", bodies[0])
+	}
+}
+
+// TestHTTPWriterFlushInterval tests that an incomplete batch is sent after the flush interval
+func TestHTTPWriterFlushInterval(t *testing.T) {
+	c, server := newCollector(t)
+	useHTTPWriter(t, server.URL, zlog.HTTPFlushInterval(20*time.Millisecond))
+
+	zlog.Info().Message("lonely entry")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(c.received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(c.received()) != 1 {
+		t.Fatal("Expected pending entry to be flushed by the interval")
+	}
+}
+
+// TestHTTPWriterRetry tests exponential backoff retries on server errors
+func TestHTTPWriterRetry(t *testing.T) {
+	c, server := newCollector(t)
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPRetry(3, time.Millisecond, 4*time.Millisecond),
+	)
+
+	c.status.Store(http.StatusServiceUnavailable)
+	go func() {
+		for c.attempts.Load() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		c.status.Store(http.StatusOK)
+	}()
+
+	zlog.Info().Message("retried")
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if len(c.received()) != 1 {
+		t.Fatalf("Expected batch to be delivered after retries, got %d bodies", len(c.received()))
+	}
+	if c.attempts.Load() < 3 {
+		t.Errorf("Expected at least 3 attempts, got %d", c.attempts.Load())
+	}
+	if writer.Dropped() != 0 {
+		t.Errorf("Expected no dropped entries, got %d", writer.Dropped())
+	}
+}
+
+// TestHTTPWriterSpillAndReplay tests spilling to disk while the collector is down
+func TestHTTPWriterSpillAndReplay(t *testing.T) {
+	c, server := newCollector(t)
+	spillDir := t.TempDir()
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPRetry(0, time.Millisecond, time.Millisecond),
+		zlog.HTTPSpillDir(spillDir, 1<<20),
+	)
+
+	c.status.Store(http.StatusBadGateway)
+	zlog.Info().Message("first")
+	_ = writer.Flush()
+	zlog.Info().Message("second")
+	_ = writer.Flush()
+
+	spilled, _ := filepath.Glob(filepath.Join(spillDir, "*.ndjson"))
+	if len(spilled) != 2 {
+		t.Fatalf("Expected 2 spilled batches, got %d", len(spilled))
+	}
+
+	c.status.Store(http.StatusOK)
+	zlog.Info().Message("third")
+	_ = writer.Flush()
+
+	var messages []string
+	for _, body := range c.received() {
+		for _, line := range ndjsonLines(body) {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Invalid entry %q: %v", line, err)
+			}
+			messages = append(messages, entry["msg"].(string))
+		}
+	}
+	if strings.Join(messages, ",") != "first,second,third" {
+		t.Errorf("Expected spilled entries replayed in order, got %v", messages)
+	}
+
+	spilled, _ = filepath.Glob(filepath.Join(spillDir, "*.ndjson"))
+	if len(spilled) != 0 {
+		t.Errorf("Expected spill directory to be empty after replay, got %d files", len(spilled))
+	}
+}
+
+// TestHTTPWriterReplayEarlierRun tests that batches spilled before the writer was created are sent first
+func TestHTTPWriterReplayEarlierRun(t *testing.T) {
+	c, server := newCollector(t)
+	spillDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(spillDir, "zlog-spill-1.ndjson"), []byte(`{"msg":"earlier"}`+"\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write spilled batch: %v", err)
+	}
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPSpillDir(spillDir, 1<<20),
+	)
+
+	zlog.Info().Message("later")
+	_ = writer.Flush()
+	bodies := c.received()
+	if len(bodies) != 2 || !strings.Contains(string(bodies[0]), "earlier") || !strings.Contains(string(bodies[1]), "later") {
+		t.Errorf("Expected the earlier batch first, got %q", bodies)
+	}
+}
+
+// TestHTTPWriterRejectedBatch tests that client errors are not retried
+func TestHTTPWriterRejectedBatch(t *testing.T) {
+	c, server := newCollector(t)
+	var fallback bytes.Buffer
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPRetry(3, time.Millisecond, time.Millisecond),
+		zlog.HTTPFallback(&fallback),
+	)
+
+	c.status.Store(http.StatusBadRequest)
+	zlog.Info().Message("rejected")
+	_ = writer.Flush()
+
+	if c.attempts.Load() != 1 {
+		t.Errorf("Expected a single attempt for 400, got %d", c.attempts.Load())
+	}
+	if writer.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped entry, got %d", writer.Dropped())
+	}
+	logData, err := parseLogOutput(fallback.String())
+	if err != nil {
+		t.Fatalf("Failed to parse fallback output: %v", err)
+	}
+	if logData["segment"] != "zlog/http" || logData["level"] != "ERROR" {
+		t.Errorf("Unexpected fallback entry: %v", logData)
+	}
+}
+
+// TestHTTPWriterLokiEncoder tests the Loki push API format
+func TestHTTPWriterLokiEncoder(t *testing.T) {
+	c, server := newCollector(t)
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPEncoding(zlog.LokiEncoder(map[string]string{"app": "orders"})),
+	)
+
+	zlog.Info().Segment("payment").Message("a")
+	zlog.Info().Segment("payment").Message("b")
+	zlog.Error().Message("c")
+	_ = writer.Flush()
+
+	bodies := c.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 push request, got %d", len(bodies))
+	}
+	var push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(bodies[0], &push); err != nil {
+		t.Fatalf("Invalid Loki payload: %v", err)
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("Expected 2 streams, got %d", len(push.Streams))
+	}
+
+	first := push.Streams[0]
+	if first.Stream["app"] != "orders" || first.Stream["level"] != "info" || first.Stream["segment"] != "payment" {
+		t.Errorf("Unexpected labels: %v", first.Stream)
+	}
+	if len(first.Values) != 2 || !strings.Contains(first.Values[1][1], `"msg":"b"`) {
+		t.Errorf("Unexpected values: %v", first.Values)
+	}
+	if _, ok := push.Streams[1].Stream["segment"]; ok || push.Streams[1].Stream["level"] != "error" {
+		t.Errorf("Unexpected labels: %v", push.Streams[1].Stream)
+	}
+}
+
+// TestHTTPWriterElasticsearchEncoder tests the _bulk API format
+func TestHTTPWriterElasticsearchEncoder(t *testing.T) {
+	c, server := newCollector(t)
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPEncoding(zlog.ElasticsearchEncoder("logs-app")),
+	)
+
+	zlog.Warn().Message("a")
+	zlog.Warn().Message("b")
+	_ = writer.Flush()
+
+	bodies := c.received()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 bulk request, got %d", len(bodies))
+	}
+	lines := ndjsonLines(bodies[0])
+	if len(lines) != 4 {
+		t.Fatalf("Expected action and document lines, got %d lines", len(lines))
+	}
+	if lines[0] != `{"index":{"_index":"logs-app"}}` {
+		t.Errorf("Unexpected action line: %s", lines[0])
+	}
+	if !strings.Contains(lines[3], `"msg":"b"`) {
+		t.Errorf("Unexpected document line: %s", lines[3])
+	}
+}
+
+// TestHTTPWriterElasticsearchItemErrors tests that failed _bulk items are retried or reported, not counted as delivered
+func TestHTTPWriterElasticsearchItemErrors(t *testing.T) {
+	var mu sync.Mutex
+	var indexed []string
+	busy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lines := ndjsonLines(body)
+		mu.Lock()
+		defer mu.Unlock()
+		var items []string
+		for i := 1; i < len(lines); i += 2 {
+			var entry map[string]any
+			_ = json.Unmarshal([]byte(lines[i]), &entry)
+			switch msg := entry["msg"].(string); {
+			case msg == "bad":
+				items = append(items, `{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}`)
+			case msg == "busy" && busy:
+				busy = false
+				items = append(items, `{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}}`)
+			default:
+				indexed = append(indexed, msg)
+				items = append(items, `{"index":{"status":201}}`)
+			}
+		}
+		_, _ = w.Write([]byte(`{"errors":true,"items":[` + strings.Join(items, ",") + `]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var fallback bytes.Buffer
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPRetry(3, time.Millisecond, time.Millisecond),
+		zlog.HTTPEncoding(zlog.ElasticsearchEncoder("logs-app")),
+		zlog.HTTPFallback(&fallback),
+	)
+
+	zlog.Info().Message("ok")
+	zlog.Info().Message("bad")
+	zlog.Info().Message("busy")
+	_ = writer.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(indexed, ",") != "ok,busy" {
+		t.Errorf("Expected the 429 item to be retried alone, indexed %v", indexed)
+	}
+	if writer.Dropped() != 1 {
+		t.Errorf("Expected the rejected item to be dropped, got %d dropped", writer.Dropped())
+	}
+	if !strings.Contains(fallback.String(), "status 400") {
+		t.Errorf("Expected the rejected item to be reported, got %q", fallback.String())
+	}
+}
+
+// TestHTTPWriterElasticsearchInvalidResponse tests that an unreadable _bulk response is retried, then spilled
+func TestHTTPWriterElasticsearchInvalidResponse(t *testing.T) {
+	var attempts atomic.Int32
+	var valid atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		_, _ = io.ReadAll(r.Body)
+		if !valid.Load() {
+			_, _ = w.Write([]byte(`<html>proxy error</html>`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	spillDir := t.TempDir()
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPRetry(2, time.Millisecond, time.Millisecond),
+		zlog.HTTPEncoding(zlog.ElasticsearchEncoder("logs-app")),
+		zlog.HTTPSpillDir(spillDir, 1<<20),
+	)
+
+	zlog.Info().Message("kept")
+	_ = writer.Flush()
+	if attempts.Load() != 3 {
+		t.Errorf("Expected the batch to be retried twice, got %d attempts", attempts.Load())
+	}
+	if spilled, _ := filepath.Glob(filepath.Join(spillDir, "*.ndjson")); len(spilled) != 1 || writer.Dropped() != 0 {
+		t.Fatalf("Expected the batch to be spilled, got %d files and %d dropped", len(spilled), writer.Dropped())
+	}
+
+	valid.Store(true)
+	zlog.Info().Message("next")
+	_ = writer.Flush()
+	if spilled, _ := filepath.Glob(filepath.Join(spillDir, "*.ndjson")); len(spilled) != 0 {
+		t.Errorf("Expected the spilled batch to be replayed, got %d files", len(spilled))
+	}
+}
+
+// TestHTTPWriterQueueFull tests that logging does not wait for a stalled collector
+// and that the entries kept are delivered in order
+func TestHTTPWriterQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var messages []string
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			<-release
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, line := range ndjsonLines(body) {
+			var entry map[string]any
+			_ = json.Unmarshal([]byte(line), &entry)
+			messages = append(messages, entry["msg"].(string))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	writer := useHTTPWriter(t, server.URL,
+		zlog.HTTPBatch(1, 1<<20),
+		zlog.HTTPFlushInterval(time.Hour),
+		zlog.HTTPSpillDir(t.TempDir(), 1<<20),
+	)
+
+	const total = 60
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		zlog.Info().Message(strconv.Itoa(i))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected logging not to wait for the collector, took %v", elapsed)
+	}
+	close(release)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if uint64(len(messages))+writer.Dropped() != total || writer.Dropped() == 0 {
+		t.Fatalf("Expected %d entries delivered or dropped with some dropped, got %d delivered and %d dropped",
+			total, len(messages), writer.Dropped())
+	}
+	for i := 1; i < len(messages); i++ {
+		previous, _ := strconv.Atoi(messages[i-1])
+		current, _ := strconv.Atoi(messages[i])
+		if current <= previous {
+			t.Fatalf("Expected entries in order, got %v", messages)
+		}
+	}
+}
+
+// TestHTTPWriterFatal tests that Fatal delivers the pending batch, including the fatal entry, before exiting
+func TestHTTPWriterFatal(t *testing.T) {
+	if os.Getenv(fatalEnv) == t.Name() {
+		writer, err := zlog.NewHTTPWriter(os.Getenv("ZLOG_TEST_COLLECTOR"), zlog.HTTPFlushInterval(time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to create HTTP writer: %v", err)
+		}
+		zlog.SetOutputWriter(writer)
+		zlog.Info().Message("before")
+		zlog.Error().Fatal("giving up")
+		return
+	}
+
+	c, server := newCollector(t)
+	runFatal(t, t.Name(), "ZLOG_TEST_COLLECTOR="+server.URL)
+
+	var messages []string
+	for _, body := range c.received() {
+		for _, line := range ndjsonLines(body) {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Invalid entry %q: %v", line, err)
+			}
+			messages = append(messages, entry["msg"].(string))
+		}
+	}
+	if strings.Join(messages, ",") != "before,giving up" {
+		t.Errorf("Expected the pending entries delivered before exiting, got %v", messages)
+	}
+}
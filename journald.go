@@ -0,0 +1,187 @@
+package zlog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+// journaldOptions holds configuration for a JournaldWriter
+type journaldOptions struct {
+	socketPath string // Path of the journald native socket
+	identifier string // Value of SYSLOG_IDENTIFIER (default: executable name)
+}
+
+type JournaldOption = func(options *journaldOptions)
+
+// JournaldSocket overrides the path of the journald native protocol socket.
+func JournaldSocket(path string) JournaldOption {
+	return func(options *journaldOptions) {
+		options.socketPath = path
+	}
+}
+
+// JournaldIdentifier sets the SYSLOG_IDENTIFIER field sent with every entry.
+func JournaldIdentifier(identifier string) JournaldOption {
+	return func(options *journaldOptions) {
+		options.identifier = identifier
+	}
+}
+
+// JournaldWriter is an io.Writer that forwards zlog JSON entries to systemd-journald
+// using the native journal protocol, so each entry keeps its level as PRIORITY.
+type JournaldWriter struct {
+	mu         sync.Mutex
+	conn       *net.UnixConn
+	socketAddr *net.UnixAddr
+	identifier string
+}
+
+// NewJournaldWriter opens a datagram socket for sending entries to the local journald.
+// The returned writer is meant to be passed to SetOutputWriter.
+//
+// Fields are mapped as follows:
+//   - level → PRIORITY (syslog severity)
+//   - msg → MESSAGE
+//   - source → CODE_FUNC, CODE_FILE, CODE_LINE
+//   - every other attribute → its key in upper case (e.g. error_msg → ERROR_MSG)
+//
+// Example:
+//
+//	writer, err := zlog.NewJournaldWriter()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer writer.Close()
+//	zlog.SetOutputWriter(writer)
+func NewJournaldWriter(options ...JournaldOption) (*JournaldWriter, error) {
+	opts := journaldOptions{
+		socketPath: defaultJournaldSocket,
+		identifier: filepath.Base(os.Args[0]),
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	addr := &net.UnixAddr{Name: opts.socketPath, Net: "unixgram"}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = os.Stat(opts.socketPath); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &JournaldWriter{
+		conn:       conn,
+		socketAddr: addr,
+		identifier: opts.identifier,
+	}, nil
+}
+
+// Write sends every JSON line in p to journald as a separate journal entry. An entry counts as written
+// once it was handed to the socket, directly or in a file; if one fails, the returned count covers the
+// entries before it, so nothing is sent twice when the rest is written again.
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return decodeEntries(p, func(entry logEntry) error {
+		return w.send(w.encode(entry))
+	})
+}
+
+func (w *JournaldWriter) decodesJSON() {}
+
+// Close closes the underlying socket.
+func (w *JournaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+func (w *JournaldWriter) send(datagram []byte) error {
+	_, _, err := w.conn.WriteMsgUnix(datagram, nil, w.socketAddr)
+	if err == nil {
+		return nil
+	}
+	// Entries larger than the socket buffer have to be passed as a file descriptor.
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		return sendJournaldFile(w.conn, w.socketAddr, datagram)
+	}
+	return err
+}
+
+func (w *JournaldWriter) encode(entry logEntry) []byte {
+	var b bytes.Buffer
+	writeJournaldField(&b, "MESSAGE", entry.message)
+	writeJournaldField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(entry.level)))
+	if w.identifier != "" {
+		writeJournaldField(&b, "SYSLOG_IDENTIFIER", w.identifier)
+	}
+	if entry.source != nil {
+		writeJournaldField(&b, "CODE_FUNC", entry.source.Function)
+		writeJournaldField(&b, "CODE_FILE", entry.source.File)
+		if entry.source.Line > 0 {
+			writeJournaldField(&b, "CODE_LINE", strconv.Itoa(entry.source.Line))
+		}
+	}
+
+	keys := make([]string, 0, len(entry.attrs))
+	for key := range entry.attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := journaldFieldName(key)
+		if name == "" {
+			continue
+		}
+		writeJournaldField(&b, name, attrLines(entry.attrs[key]))
+	}
+	return b.Bytes()
+}
+
+// journaldFieldName converts an attribute key into a valid journal field name:
+// upper case letters, digits and underscores, not starting with a digit or underscore.
+func journaldFieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z':
+			name = append(name, c-'a'+'A')
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			name = append(name, c)
+		default:
+			name = append(name, '_')
+		}
+	}
+	return strings.TrimLeft(string(name), "_0123456789")
+}
+
+// writeJournaldField appends a field using the native protocol.
+// Values containing a newline use the binary length-prefixed form.
+func writeJournaldField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.ContainsRune(value, '\n') {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b.Write(size[:])
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
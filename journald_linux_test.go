@@ -0,0 +1,49 @@
+package zlog_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestJournaldWriterLargeEntry tests that entries too large for a datagram are passed as a sealed memfd
+func TestJournaldWriterLargeEntry(t *testing.T) {
+	server, _ := listenJournald(t)
+	message := strings.Repeat("x", 1<<20)
+	zlog.Error().Message(message)
+
+	_ = server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := server.ReadMsgUnix(nil, oob)
+	if err != nil {
+		t.Fatalf("Failed to read journald datagram: %v", err)
+	}
+	messages, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("Expected a file descriptor, got %v %v", messages, err)
+	}
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("Expected a file descriptor, got %v %v", fds, err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "journal")
+	defer file.Close()
+
+	const fGetSeals, sealWrite = 0x40a, 0x8
+	seals, _, errno := syscall.Syscall(syscall.SYS_FCNTL, file.Fd(), fGetSeals, 0)
+	if errno != 0 || seals&sealWrite == 0 {
+		t.Errorf("Expected a write-sealed memfd, got seals %#x (%v)", seals, errno)
+	}
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, 1<<30))
+	if err != nil {
+		t.Fatalf("Failed to read the passed file: %v", err)
+	}
+	if fields := decodeJournaldFields(t, data); fields["MESSAGE"] != message || fields["PRIORITY"] != "3" {
+		t.Errorf("Unexpected fields in the passed file: PRIORITY=%q, %d message bytes", fields["PRIORITY"], len(fields["MESSAGE"]))
+	}
+}
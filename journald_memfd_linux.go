@@ -0,0 +1,47 @@
+package zlog
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfdCreate is the number of the memfd_create system call, which package syscall does not define
+// on every architecture; 0 if unknown
+var memfdCreate = map[string]uintptr{
+	"386": 356, "amd64": 319, "arm": 385, "arm64": 279, "loong64": 279, "mips": 4354, "mipsle": 4354,
+	"mips64": 5314, "mips64le": 5314, "ppc64": 360, "ppc64le": 360, "riscv64": 279, "s390x": 350,
+}[runtime.GOARCH]
+
+const (
+	mfdCloexec      = 0x1                   // MFD_CLOEXEC
+	mfdAllowSealing = 0x2                   // MFD_ALLOW_SEALING
+	fAddSeals       = 0x409                 // F_ADD_SEALS
+	memfdSeals      = 0x1 | 0x2 | 0x4 | 0x8 // F_SEAL_SEAL, F_SEAL_SHRINK, F_SEAL_GROW and F_SEAL_WRITE
+)
+
+// sealedMemfd returns a memfd holding data, sealed so that journald can map it without copying
+func sealedMemfd(data []byte) (*os.File, error) {
+	if memfdCreate == 0 {
+		return nil, syscall.ENOSYS
+	}
+	name, err := syscall.BytePtrFromString("zlog-journal")
+	if err != nil {
+		return nil, err
+	}
+	fd, _, errno := syscall.Syscall(memfdCreate, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	file := os.NewFile(fd, "zlog-journal")
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if _, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, memfdSeals); errno != 0 {
+		_ = file.Close()
+		return nil, errno
+	}
+	return file, nil
+}
@@ -0,0 +1,13 @@
+//go:build unix && !linux
+
+package zlog
+
+import (
+	"os"
+	"syscall"
+)
+
+// sealedMemfd is only available on Linux, other systems use a temporary file
+func sealedMemfd([]byte) (*os.File, error) {
+	return nil, syscall.ENOSYS
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package zlog
+
+import (
+	"errors"
+	"net"
+)
+
+func sendJournaldFile(conn *net.UnixConn, addr *net.UnixAddr, datagram []byte) error {
+	return errors.New("zlog: journald entry too large for a single datagram")
+}
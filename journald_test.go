@@ -0,0 +1,239 @@
+package zlog_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// listenJournald starts a fake journald socket and returns a writer connected to it
+func listenJournald(t *testing.T) (*net.UnixConn, *zlog.JournaldWriter) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("journald is not available on windows")
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "journal.socket")
+	server, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen on fake journald socket: %v", err)
+	}
+	writer, err := zlog.NewJournaldWriter(zlog.JournaldSocket(socketPath), zlog.JournaldIdentifier("zlog-test"))
+	if err != nil {
+		t.Fatalf("Failed to create journald writer: %v", err)
+	}
+
+	zlog.SetOutputWriter(writer)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		_ = writer.Close()
+		_ = server.Close()
+	})
+	return server, writer
+}
+
+// readJournaldFields reads one datagram and decodes the native protocol fields
+func readJournaldFields(t *testing.T, server *net.UnixConn) map[string]string {
+	t.Helper()
+	_ = server.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 65536)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read journald datagram: %v", err)
+	}
+
+	return decodeJournaldFields(t, buf[:n])
+}
+
+// decodeJournaldFields decodes the native protocol fields of a datagram
+func decodeJournaldFields(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(data) > 0 {
+		newline := bytes.IndexByte(data, '\n')
+		if newline == -1 {
+			t.Fatalf("Malformed datagram: %q", data)
+		}
+		line := data[:newline]
+		if name, value, ok := bytes.Cut(line, []byte{'='}); ok {
+			fields[string(name)] = string(value)
+			data = data[newline+1:]
+			continue
+		}
+		// Binary form: NAME\n<uint64 little endian length><value>\n
+		size := binary.LittleEndian.Uint64(data[newline+1 : newline+9])
+		start := newline + 9
+		fields[string(line)] = string(data[start : start+int(size)])
+		data = data[start+int(size)+1:]
+	}
+	return fields
+}
+
+// TestJournaldWriterFields tests mapping of level, message, source and attributes
+func TestJournaldWriterFields(t *testing.T) {
+	server, _ := listenJournald(t)
+
+	zlog.Error().
+		WithSource().
+		Segment("payment", "process").
+		Err(errors.New("gateway timeout")).
+		KeyValue("order-id", "ord-1").
+		Message("Payment failed")
+
+	fields := readJournaldFields(t, server)
+
+	expected := map[string]string{
+		"MESSAGE":           "Payment failed",
+		"PRIORITY":          "3",
+		"SYSLOG_IDENTIFIER": "zlog-test",
+		"SEGMENT":           "payment/process",
+		"ERROR_MSG":         "gateway timeout",
+		"ORDER_ID":          "ord-1",
+	}
+	for key, value := range expected {
+		if fields[key] != value {
+			t.Errorf("Expected %s=%q, got %q", key, value, fields[key])
+		}
+	}
+
+	if !strings.HasSuffix(fields["CODE_FILE"], "journald_test.go") {
+		t.Errorf("Expected CODE_FILE to point to the test file, got %q", fields["CODE_FILE"])
+	}
+	if fields["CODE_LINE"] == "" || fields["CODE_LINE"] == "0" {
+		t.Errorf("Expected CODE_LINE to be set, got %q", fields["CODE_LINE"])
+	}
+	if !strings.Contains(fields["CODE_FUNC"], "TestJournaldWriterFields") {
+		t.Errorf("Expected CODE_FUNC to contain the test name, got %q", fields["CODE_FUNC"])
+	}
+	if _, ok := fields["TIME"]; ok {
+		t.Error("Expected time to be left to journald")
+	}
+}
+
+// TestJournaldWriterPriority tests level to PRIORITY mapping
+func TestJournaldWriterPriority(t *testing.T) {
+	server, _ := listenJournald(t)
+
+	tests := []struct {
+		logFunc          func()
+		expectedPriority string
+	}{
+		{func() { zlog.Trace().Message("trace") }, "7"},
+		{func() { zlog.Debug().Message("debug") }, "7"},
+		{func() { zlog.Info().Message("info") }, "6"},
+		{func() { zlog.Notice().Message("notice") }, "5"},
+		{func() { zlog.Warn().Message("warn") }, "4"},
+		{func() { zlog.Error().Message("error") }, "3"},
+		{func() { zlog.Critical().Message("critical") }, "2"},
+	}
+
+	for _, tt := range tests {
+		tt.logFunc()
+		fields := readJournaldFields(t, server)
+		if fields["PRIORITY"] != tt.expectedPriority {
+			t.Errorf("Expected PRIORITY=%s for %q, got %s", tt.expectedPriority, fields["MESSAGE"], fields["PRIORITY"])
+		}
+	}
+}
+
+// TestJournaldWriterCallStack tests that multi-line values use the binary field format
+func TestJournaldWriterCallStack(t *testing.T) {
+	server, _ := listenJournald(t)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.AutoCallStackConfig(slog.LevelError, true),
+	))
+	var deepFunc func(int)
+	deepFunc = func(depth int) {
+		if depth == 0 {
+			zlog.Error().Message("deep")
+			return
+		}
+		deepFunc(depth - 1)
+	}
+	deepFunc(3)
+
+	fields := readJournaldFields(t, server)
+	frames := strings.Split(fields["CALLSTACK"], "\n")
+	if len(frames) < 2 {
+		t.Fatalf("Expected multiple callstack frames, got %q", fields["CALLSTACK"])
+	}
+	for _, frame := range frames {
+		if !strings.HasPrefix(frame, "#") {
+			t.Errorf("Unexpected callstack frame: %q", frame)
+		}
+	}
+}
+
+// TestJournaldWriterWriteCount tests that Write reports the entries handed to journald
+func TestJournaldWriterWriteCount(t *testing.T) {
+	server, writer := listenJournald(t)
+
+	p := []byte(`{"level":"INFO","msg":"first"}` + "\n" + `{"level":"INFO","msg":"second"}` + "\n")
+	if n, err := writer.Write(p); n != len(p) || err != nil {
+		t.Errorf("Expected %d bytes written, got %d, %v", len(p), n, err)
+	}
+	for _, expected := range []string{"first", "second"} {
+		if fields := readJournaldFields(t, server); fields["MESSAGE"] != expected {
+			t.Errorf("Expected MESSAGE=%s, got %v", expected, fields)
+		}
+	}
+
+	_ = server.Close()
+	if n, err := writer.Write(p); n != 0 || err == nil {
+		t.Errorf("Expected nothing written without journald, got %d, %v", n, err)
+	}
+}
+
+// TestJournaldWriterTextFormat tests that the writer still gets JSON entries with the text format configured
+func TestJournaldWriterTextFormat(t *testing.T) {
+	server, _ := listenJournald(t)
+	zlog.SetConfig(zlog.Configure(zlog.FormatConfig(zlog.FormatText)))
+
+	zlog.Warn().KeyValue("order_id", "42").Message("decoded")
+	fields := readJournaldFields(t, server)
+	if fields["MESSAGE"] != "decoded" || fields["PRIORITY"] != "4" || fields["ORDER_ID"] != "42" {
+		t.Errorf("Expected the entry fields, got %v", fields)
+	}
+}
+
+// TestJournaldWriterMissingSocket tests that a missing socket is reported on creation
+func TestJournaldWriterMissingSocket(t *testing.T) {
+	_, err := zlog.NewJournaldWriter(zlog.JournaldSocket(filepath.Join(t.TempDir(), "missing.socket")))
+	if err == nil {
+		t.Error("Expected error for missing journald socket")
+	}
+}
+
+// TestJournaldWriterStructuredSource tests that a structured source maps to the same CODE_* fields
+func TestJournaldWriterStructuredSource(t *testing.T) {
+	server, _ := listenJournald(t)
+	zlog.SetConfig(zlog.Configure(zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured)))
+
+	zlog.Error().WithSource().WithCallStack().Message("Payment failed")
+
+	fields := readJournaldFields(t, server)
+	if !strings.HasSuffix(fields["CODE_FILE"], "journald_test.go") {
+		t.Errorf("Expected CODE_FILE to point to the test file, got %q", fields["CODE_FILE"])
+	}
+	if fields["CODE_LINE"] == "" || fields["CODE_LINE"] == "0" {
+		t.Errorf("Expected CODE_LINE to be set, got %q", fields["CODE_LINE"])
+	}
+	if !strings.HasSuffix(fields["CODE_FUNC"], ".TestJournaldWriterStructuredSource") {
+		t.Errorf("Expected CODE_FUNC to be the test function, got %q", fields["CODE_FUNC"])
+	}
+	if first, _, _ := strings.Cut(fields["CALLSTACK"], "\n"); !strings.Contains(first, "TestJournaldWriterStructuredSource @ ") {
+		t.Errorf("Expected one frame per line in CALLSTACK, got %q", fields["CALLSTACK"])
+	}
+}
@@ -0,0 +1,45 @@
+//go:build unix
+
+package zlog
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendJournaldFile passes an oversized entry to journald through a file, as described by the native
+// protocol: the datagram carries only the file descriptor. The file is a sealed memfd where the kernel
+// supports it, otherwise an unlinked temporary file, which journald accepts as well.
+func sendJournaldFile(conn *net.UnixConn, addr *net.UnixAddr, datagram []byte) error {
+	file, err := sealedMemfd(datagram)
+	if err != nil {
+		if file, err = unlinkedTempFile(datagram); err != nil {
+			return err
+		}
+	}
+	defer file.Close()
+	_, _, err = conn.WriteMsgUnix(nil, syscall.UnixRights(int(file.Fd())), addr)
+	return err
+}
+
+// unlinkedTempFile returns a temporary file holding data, preferably on the tmpfs /dev/shm,
+// removed from its directory so it disappears once closed
+func unlinkedTempFile(data []byte) (*os.File, error) {
+	dir := "/dev/shm"
+	if _, err := os.Stat(dir); err != nil {
+		dir = os.TempDir()
+	}
+	file, err := os.CreateTemp(dir, "zlog-journal-")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Remove(file.Name()); err == nil {
+		_, err = file.Write(data)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
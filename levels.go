@@ -0,0 +1,128 @@
+package zlog
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Additional levels on top of slog's Debug (-4), Info (0), Warn (4) and Error (8).
+const (
+	LevelTrace    slog.Level = -8 // Very fine-grained tracing, below Debug
+	LevelNotice   slog.Level = 2  // Normal but significant events, between Info and Warn
+	LevelCritical slog.Level = 12 // Critical conditions, above Error
+)
+
+// levelRegistry maps levels to the names written in the "level" field and used as config keys.
+// It is replaced as a whole on registration, so lookups on the logging path need no locking.
+type levelRegistry struct {
+	names  map[slog.Level]string // level -> output name ("TRACE")
+	byName map[string]slog.Level // lower case name -> level ("trace")
+}
+
+var (
+	levels   atomic.Pointer[levelRegistry]
+	levelsMu sync.Mutex
+
+	builtinLevels = map[slog.Level]string{
+		LevelTrace:      "TRACE",
+		slog.LevelDebug: "DEBUG",
+		slog.LevelInfo:  "INFO",
+		LevelNotice:     "NOTICE",
+		slog.LevelWarn:  "WARN",
+		slog.LevelError: "ERROR",
+		LevelCritical:   "CRITICAL",
+	}
+
+	// Keys of the configuration file that are not level names
+	reservedConfigKeys = map[string]struct{}{"minlevel": {}, "format": {}, "output": {}, "segments": {}, "packages": {}, "sampling": {}, "ratelimit": {}, "dedupwindow": {}, "redaction": {}, "sizelimits": {}, "framefilter": {}, "goroutinedump": {}, schemaKey: {}}
+)
+
+func init() {
+	registry := &levelRegistry{
+		names:  make(map[slog.Level]string, len(builtinLevels)),
+		byName: make(map[string]slog.Level, len(builtinLevels)),
+	}
+	for level, name := range builtinLevels {
+		registry.names[level] = name
+		registry.byName[strings.ToLower(name)] = level
+	}
+	levels.Store(registry)
+}
+
+// RegisterLevel adds a custom level with the given name and numeric value.
+// The name is written upper case in the "level" field and can be used (in any case)
+// as a key in JSON configuration files. Built-in levels cannot be renamed.
+//
+// Example:
+//
+//	const LevelAudit = slog.Level(6)
+//	if err := zlog.RegisterLevel("audit", LevelAudit); err != nil {
+//	    log.Fatal(err)
+//	}
+//	zlog.Log(LevelAudit).KeyValue("user", "admin").Message("Permissions changed")
+//	// Output: {"time":"2024-03-07T10:00:00Z","level":"AUDIT","user":"admin","msg":"Permissions changed"}
+func RegisterLevel(name string, level slog.Level) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("zlog: level name must not be empty")
+	}
+	upper, lower := strings.ToUpper(name), strings.ToLower(name)
+	if _, ok := reservedConfigKeys[lower]; ok {
+		return fmt.Errorf("zlog: level name %q is reserved for configuration", name)
+	}
+
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+
+	current := levels.Load()
+	if existing, ok := current.byName[lower]; ok {
+		if existing == level {
+			return nil
+		}
+		return fmt.Errorf("zlog: level name %q is already registered for level %d", name, existing)
+	}
+	if _, ok := builtinLevels[level]; ok {
+		return fmt.Errorf("zlog: level %d is built in as %s", level, builtinLevels[level])
+	}
+
+	registry := &levelRegistry{
+		names:  make(map[slog.Level]string, len(current.names)+1),
+		byName: make(map[string]slog.Level, len(current.byName)+1),
+	}
+	for existingLevel, existingName := range current.names {
+		if existingLevel == level {
+			continue // Re-registering a custom level renames it
+		}
+		registry.names[existingLevel] = existingName
+		registry.byName[strings.ToLower(existingName)] = existingLevel
+	}
+	registry.names[level] = upper
+	registry.byName[lower] = level
+	levels.Store(registry)
+	return nil
+}
+
+// levelName returns the output name of a level. Unregistered levels use slog's
+// relative notation, e.g. "INFO+1".
+func levelName(level slog.Level) string {
+	if name, ok := levels.Load().names[level]; ok {
+		return name
+	}
+	return level.String()
+}
+
+// parseLevel resolves a level from a registered name (case-insensitive) or slog's notation ("warn+2").
+func parseLevel(name string) (slog.Level, error) {
+	if level, ok := levels.Load().byName[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return level, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("zlog: unknown level %q", name)
+	}
+	return level, nil
+}
@@ -0,0 +1,151 @@
+package zlog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+const levelAudit = slog.Level(6)
+
+// TestAdditionalLevels tests the Trace, Notice and Critical entry points
+func TestAdditionalLevels(t *testing.T) {
+	tests := []struct {
+		name          string
+		logFunc       func()
+		expectedLevel string
+	}{
+		{"Trace level", func() { zlog.Trace().Message("trace message") }, "TRACE"},
+		{"Notice level", func() { zlog.Notice().Message("notice message") }, "NOTICE"},
+		{"Critical level", func() { zlog.Critical().Message("critical message") }, "CRITICAL"},
+		{"Log with built-in level", func() { zlog.Log(slog.LevelWarn).Message("warn message") }, "WARN"},
+		{"Log with unregistered level", func() { zlog.Log(slog.LevelInfo + 1).Message("odd message") }, "INFO+1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			setupTestLogger(&buf)
+
+			tt.logFunc()
+
+			logData, err := parseLogOutput(buf.String())
+			if err != nil {
+				t.Fatalf("Failed to parse log output: %v", err)
+			}
+			if logData["level"] != tt.expectedLevel {
+				t.Errorf("Expected level %s, got %v", tt.expectedLevel, logData["level"])
+			}
+		})
+	}
+}
+
+// TestRegisterLevel tests registering and logging at a custom level
+func TestRegisterLevel(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	if err := zlog.RegisterLevel("audit", levelAudit); err != nil {
+		t.Fatalf("Failed to register level: %v", err)
+	}
+	// Registering the same pair again is a no-op
+	if err := zlog.RegisterLevel("AUDIT", levelAudit); err != nil {
+		t.Errorf("Expected re-registration to succeed, got %v", err)
+	}
+
+	zlog.SetConfig(zlog.Configure(zlog.AutoSourceConfig(levelAudit, true)))
+	zlog.Log(levelAudit).KeyValue("user", "admin").Message("Permissions changed")
+
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if logData["level"] != "AUDIT" {
+		t.Errorf("Expected level AUDIT, got %v", logData["level"])
+	}
+	if _, ok := logData["source"]; !ok {
+		t.Error("Expected auto source for custom level")
+	}
+}
+
+// TestRegisterLevelErrors tests invalid registrations
+func TestRegisterLevelErrors(t *testing.T) {
+	if err := zlog.RegisterLevel("", slog.Level(42)); err == nil {
+		t.Error("Expected error for empty name")
+	}
+	if err := zlog.RegisterLevel("fatal", slog.LevelError); err == nil {
+		t.Error("Expected error when renaming a built-in level")
+	}
+	if err := zlog.RegisterLevel("warn", slog.Level(42)); err == nil {
+		t.Error("Expected error when reusing a built-in name")
+	}
+	if err := zlog.RegisterLevel("format", slog.Level(42)); err == nil {
+		t.Error("Expected error for a reserved configuration key")
+	}
+}
+
+// TestCustomLevelAutoFeatures tests that auto features apply to levels other than the original four
+func TestCustomLevelAutoFeatures(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.AutoSourceConfig(zlog.LevelTrace, true),
+		zlog.AutoCallStackConfig(zlog.LevelCritical, true),
+		zlog.MaxCallStackDepthConfig(zlog.LevelCritical, 3),
+	))
+
+	zlog.Trace().Message("trace")
+	zlog.Critical().Message("critical")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d", len(lines))
+	}
+	traceData, _ := parseLogOutput(string(lines[0]))
+	if _, ok := traceData["source"]; !ok {
+		t.Error("Expected source for Trace")
+	}
+	criticalData, _ := parseLogOutput(string(lines[1]))
+	if _, ok := criticalData["callstack"]; !ok {
+		t.Error("Expected callstack for Critical")
+	}
+}
+
+// TestConfigureFromJSONFileCustomLevels tests level names as JSON configuration keys
+func TestConfigureFromJSONFileCustomLevels(t *testing.T) {
+	if err := zlog.RegisterLevel("audit", levelAudit); err != nil {
+		t.Fatalf("Failed to register level: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "levels.json")
+	config := `{"trace": {"autoSource": true}, "AUDIT": {"autoSource": true}, "error": {"autoSource": false}}`
+	if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	zlog.SetConfig(zlog.ConfigureFromJSONFile(configPath))
+
+	zlog.Trace().Message("trace")
+	zlog.Log(levelAudit).Message("audit")
+	zlog.Error().Message("error")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 log lines, got %d", len(lines))
+	}
+	for i, expectSource := range []bool{true, true, false} {
+		logData, err := parseLogOutput(string(lines[i]))
+		if err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		if _, hasSource := logData["source"]; hasSource != expectSource {
+			t.Errorf("Line %d: expected source presence=%v", i, expectSource)
+		}
+	}
+}
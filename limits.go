@@ -0,0 +1,246 @@
+package zlog
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// limiterSweepSize is the number of tracked keys of a limiter shard above which idle ones are dropped
+const limiterSweepSize = 1024
+
+// RateLimit is a token bucket applied to each (level, segment, message): up to Burst entries
+// are written at once, and the bucket refills by one entry every Every.
+type RateLimit struct {
+	Every time.Duration
+	Burst int
+}
+
+// RateLimitConfig limits every (level, segment, message) to burst entries at once, refilled by one entry every interval.
+// The next entry written after some were dropped carries a "rate_limited" field with their count; if none is
+// written within an interval after the bucket refilled, the count is written with the attributes of the last one.
+//
+// Example:
+//
+//	// At most 10 identical entries at once, then one per second
+//	zlog.SetConfig(zlog.Configure(zlog.RateLimitConfig(time.Second, 10)))
+func RateLimitConfig(every time.Duration, burst int) Configurable {
+	return func(config *Config) {
+		config.RateLimit = &RateLimit{Every: every, Burst: burst}
+	}
+}
+
+// DedupConfig collapses the repeats of an entry: the first (level, segment, message) is written, the repeats
+// within window are counted and written as a single entry with the attributes of the first and a "repeated"
+// field when the window ends.
+// Every overrides the window for a single call.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.DedupConfig(time.Minute)))
+func DedupConfig(window time.Duration) Configurable {
+	return func(config *Config) {
+		config.DedupWindow = window
+	}
+}
+
+// Every writes the entry at most once per interval for its level, segment and message, the repeats
+// within the interval are written as a single entry with a "repeated" field when it ends.
+// It overrides DedupConfig for this call.
+//
+// Example:
+//
+//	zlog.Error().Segment("db").Err(err).Every(time.Minute).Msg("Connection failed")
+//	// Output: {"level":"ERROR","msg":"Connection failed","segment":"db","error_msg":"connection refused"}
+//	// A minute later: {"level":"ERROR","msg":"Connection failed","segment":"db","error_msg":"connection refused","repeated":5999}
+func (z *zlogImpl) Every(interval time.Duration) ZLogger {
+	z.every = interval
+	return z
+}
+
+// MarshalJSON writes the interval as a string ("1s")
+func (r RateLimit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{"every": r.Every.String(), "burst": r.Burst})
+}
+
+// UnmarshalJSON reads a rate limit written by MarshalJSON
+func (r *RateLimit) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Every string `json:"every"`
+		Burst int    `json:"burst"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	every, err := parseOptionalDuration(fields.Every)
+	if err != nil {
+		return err
+	}
+	r.Every, r.Burst = every, fields.Burst
+	return nil
+}
+
+// limitKey identifies the entries sharing a token bucket and a dedup window
+type limitKey struct {
+	level   slog.Level
+	segment string
+	message string
+}
+
+// limitState tracks the token bucket and the dedup window of a limitKey
+type limitState struct {
+	shard       *limiterShard
+	tokens      float64     // Entries available in the bucket as of refilled
+	refilled    int64       // Unix nanoseconds of the last refill
+	rateLimited uint64      // Entries dropped by the rate limit since the last one written
+	dedupUntil  int64       // Unix nanoseconds at which the dedup window ends
+	repeated    uint64      // Repeats suppressed in the current window
+	attrs       []slog.Attr // Attributes of the last entry written before suppressed ones, written with their count
+}
+
+// limiter applies the rate limit and duplicate suppression of an active configuration.
+// Keys are spread over shards by message hash, so unrelated messages rarely share a lock.
+type limiter struct {
+	shards [limiterShards]limiterShard
+}
+
+// limiterShards is the number of independently locked parts of a limiter
+const limiterShards = 16
+
+type limiterShard struct {
+	mu      sync.Mutex
+	states  map[limitKey]*limitState
+	sweepAt int
+}
+
+func newLimiter() *limiter {
+	l := &limiter{}
+	for i := range l.shards {
+		l.shards[i] = limiterShard{states: make(map[limitKey]*limitState), sweepAt: limiterSweepSize}
+	}
+	return l
+}
+
+// allow reports whether the entry is written, with the attributes to add to it. The entry is returned a state to
+// record its attributes in if entries suppressed after it may be reported without another one being written.
+func (l *limiter) allow(z *zlogImpl, message string, rateLimit *RateLimit, window time.Duration) ([]slog.Attr, *limitState, bool) {
+	now := time.Now().UnixNano()
+	key := limitKey{level: z.level, segment: z.segment, message: message}
+
+	shard := &l.shards[messageHash(z.level, message)%limiterShards]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	state := shard.states[key]
+	if state == nil {
+		if len(shard.states) >= shard.sweepAt {
+			shard.sweep(now, rateLimit)
+		}
+		state = &limitState{shard: shard, refilled: now}
+		if rateLimit != nil {
+			state.tokens = float64(rateLimit.Burst)
+		}
+		shard.states[key] = state
+	}
+
+	if now < state.dedupUntil {
+		state.repeated++
+		if state.repeated == 1 {
+			time.AfterFunc(time.Duration(state.dedupUntil-now), func() { flushLimited(key, state) })
+		}
+		return nil, nil, false
+	}
+
+	var extra []slog.Attr
+	var record *limitState
+	if rateLimit != nil {
+		state.refill(now, rateLimit)
+		if state.tokens < 1 {
+			state.rateLimited++
+			if state.rateLimited == 1 && rateLimit.Every > 0 {
+				// The next entry written carries the count, report it on its own if none comes
+				// within an interval after the bucket refills
+				delay := time.Duration((2 - state.tokens) * float64(rateLimit.Every))
+				time.AfterFunc(delay, func() { flushLimited(key, state) })
+			}
+			return nil, nil, false
+		}
+		state.tokens--
+		if state.rateLimited > 0 {
+			extra = append(extra, slog.Uint64("rate_limited", state.rateLimited))
+			state.rateLimited = 0
+		}
+		if state.tokens < 1 {
+			record = state
+		}
+	}
+	if window > 0 {
+		state.dedupUntil = now + int64(window)
+		record = state
+	}
+	return extra, record, true
+}
+
+// record keeps a copy of the attributes an entry was written with, for the entry reporting the ones suppressed after it
+func (s *limitState) record(attrs []slog.Attr) {
+	s.shard.mu.Lock()
+	s.attrs = slices.Clone(attrs) // The entry is recycled once written
+	s.shard.mu.Unlock()
+}
+
+// refill adds the entries earned since the last refill, up to the burst
+func (s *limitState) refill(now int64, rateLimit *RateLimit) {
+	if rateLimit.Every > 0 {
+		s.tokens += float64(now-s.refilled) / float64(rateLimit.Every)
+	}
+	if s.tokens > float64(rateLimit.Burst) {
+		s.tokens = float64(rateLimit.Burst)
+	}
+	s.refilled = now
+}
+
+// flushLimited writes the entries of key suppressed since the last one written as a single entry with
+// their count, using the attributes of that entry and the logger active when it is called
+func flushLimited(key limitKey, state *limitState) {
+	state.shard.mu.Lock()
+	repeated, rateLimited, attrs := state.repeated, state.rateLimited, state.attrs
+	state.repeated, state.rateLimited = 0, 0
+	state.shard.mu.Unlock()
+
+	if repeated == 0 && rateLimited == 0 {
+		return
+	}
+	attrs = slices.Clip(attrs)
+	if repeated > 0 {
+		attrs = append(attrs, slog.Uint64("repeated", repeated))
+	}
+	if rateLimited > 0 {
+		attrs = append(attrs, slog.Uint64("rate_limited", rateLimited))
+	}
+	active := pinnedConfig()
+	defer active.unpinOutput()
+	active.logger.LogAttrs(context.Background(), key.level, key.message, attrs...)
+}
+
+// sweep forgets the keys with a full bucket, no open dedup window and nothing left to report.
+// Called with s.mu held.
+func (s *limiterShard) sweep(now int64, rateLimit *RateLimit) {
+	for key, state := range s.states {
+		if state.repeated > 0 || state.rateLimited > 0 || now < state.dedupUntil {
+			continue
+		}
+		if rateLimit != nil {
+			state.refill(now, rateLimit)
+			if state.tokens < float64(rateLimit.Burst) {
+				continue
+			}
+		}
+		delete(s.states, key)
+	}
+	s.sweepAt = 2 * len(s.states)
+	if s.sweepAt < limiterSweepSize {
+		s.sweepAt = limiterSweepSize
+	}
+}
@@ -0,0 +1,156 @@
+package zlog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestRateLimit tests the token bucket per level, segment and message
+func TestRateLimit(t *testing.T) {
+	buf := &syncBuffer{}
+	zlog.SetOutputWriter(buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.RateLimitConfig(40*time.Millisecond, 2)))
+	for i := 0; i < 5; i++ {
+		zlog.Error().Segment("db").Msg("connection failed")
+	}
+	zlog.Error().Segment("cache").Msg("connection failed")
+
+	output := buf.String()
+	if count := strings.Count(output, `"segment":"db"`); count != 2 {
+		t.Errorf("Expected the burst of 2 db entries, got %d: %s", count, output)
+	}
+	if count := strings.Count(output, `"segment":"cache"`); count != 1 {
+		t.Errorf("Expected other segments to have their own bucket, got %d", count)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	buf.Reset()
+	zlog.Error().Segment("db").Msg("connection failed")
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Expected an entry after the refill: %v", err)
+	}
+	if logData["rate_limited"] != float64(3) {
+		t.Errorf("Expected rate_limited 3, got %v", logData["rate_limited"])
+	}
+}
+
+// TestRateLimitTrailing tests that entries dropped at the end of a burst are reported without further entries,
+// with the attributes of the entry written before them
+func TestRateLimitTrailing(t *testing.T) {
+	buf := &syncBuffer{}
+	zlog.SetOutputWriter(buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.RateLimitConfig(20*time.Millisecond, 1), zlog.AutoSourceConfig(slog.LevelError, true)))
+	for i := 0; i < 3; i++ {
+		zlog.Error().Segment("db").Msg("connection failed")
+	}
+	waitFor(t, func() bool { return strings.Count(buf.String(), "connection failed") == 2 }, "rate limited count")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	first, _ := parseLogOutput(lines[0])
+	summary, err := parseLogOutput(lines[1])
+	if err != nil {
+		t.Fatalf("Failed to parse log line: %v", err)
+	}
+	if summary["rate_limited"] != float64(2) || summary["segment"] != "db" || summary["source"] == nil || summary["source"] != first["source"] {
+		t.Errorf("Expected the count with the attributes of the first entry, got: %s", lines[1])
+	}
+}
+
+// TestDedup tests that repeats within the window are written once with their count
+func TestDedup(t *testing.T) {
+	buf := &syncBuffer{}
+	zlog.SetOutputWriter(buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.DedupConfig(50*time.Millisecond), zlog.AutoGoroutineIDConfig(slog.LevelError, true)))
+	for i := 0; i < 5; i++ {
+		zlog.Error().Err(errors.New("connection refused")).Msg("downstream failed")
+	}
+	zlog.Error().Msg("other failure")
+
+	if count := strings.Count(buf.String(), "downstream failed"); count != 1 {
+		t.Fatalf("Expected repeats to be suppressed, got: %s", buf.String())
+	}
+	waitFor(t, func() bool { return strings.Count(buf.String(), "downstream failed") == 2 }, "repeat count")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	logData, err := parseLogOutput(lines[len(lines)-1])
+	if err != nil {
+		t.Fatalf("Failed to parse log line: %v", err)
+	}
+	if logData["msg"] != "downstream failed" || logData["repeated"] != float64(4) || logData["error_msg"] != "connection refused" || logData["goroutine"] == nil {
+		t.Errorf("Expected a single line for the 4 repeats, got: %s", lines[len(lines)-1])
+	}
+	if strings.Count(buf.String(), "other failure") != 1 || strings.Contains(lines[1], "repeated") {
+		t.Errorf("Expected other messages to be unaffected, got: %s", buf.String())
+	}
+}
+
+// TestEvery tests the per call dedup window
+func TestEvery(t *testing.T) {
+	buf := &syncBuffer{}
+	zlog.SetOutputWriter(buf)
+	zlog.SetConfig(zlog.Configure())
+
+	for i := 0; i < 3; i++ {
+		zlog.Warn().Segment("retry").Every(50 * time.Millisecond).Msg("retrying")
+		zlog.Warn().Segment("retry").Msg("not limited")
+	}
+	zlog.Debug().Every(time.Minute).Msg("disabled logger")
+
+	if count := strings.Count(buf.String(), `"msg":"retrying"`); count != 1 {
+		t.Errorf("Expected a single entry within the interval, got %d", count)
+	}
+	if count := strings.Count(buf.String(), `"msg":"not limited"`); count != 3 {
+		t.Errorf("Expected entries without Every to be written, got %d", count)
+	}
+	waitFor(t, func() bool { return strings.Contains(buf.String(), `"msg":"retrying","segment":"retry","repeated":2`) }, "repeat count")
+}
+
+// TestLimitsFromFile tests rateLimit and dedupWindow in the JSON file and their round trip
+func TestLimitsFromFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{"rateLimit": {"every": "1s", "burst": 10}, "dedupWindow": "1m"}`)
+	config, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.RateLimit == nil || config.RateLimit.Every != time.Second || config.RateLimit.Burst != 10 || config.DedupWindow != time.Minute {
+		t.Fatalf("Unexpected limits: %+v %v", config.RateLimit, config.DedupWindow)
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	var decoded zlog.Config
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+	if decoded.RateLimit == nil || *decoded.RateLimit != *config.RateLimit || decoded.DedupWindow != time.Minute {
+		t.Errorf("Limits did not round trip: %s", data)
+	}
+
+	for content, expected := range map[string]string{
+		`{"rateLimit": {"every": "1s"}}`:             `rateLimit needs every and burst`,
+		`{"rateLimit": {"every": "1s", "burst": 0}}`: `rateLimit.burst must be a positive integer`,
+		`{"dedupWindow": 60}`:                        `dedupWindow must be a duration string`,
+	} {
+		writeConfigFile(t, configPath, content)
+		if _, err = zlog.LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q for %s, got %v", expected, content, err)
+		}
+	}
+}
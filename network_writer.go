@@ -0,0 +1,354 @@
+package zlog
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNetworkBufferSize   = 1000
+	defaultNetworkMinBackoff   = 100 * time.Millisecond
+	defaultNetworkMaxBackoff   = 30 * time.Second
+	defaultNetworkDialTimeout  = 5 * time.Second
+	defaultNetworkWriteTimeout = 5 * time.Second
+	defaultNetworkSyncTimeout  = 5 * time.Second
+)
+
+// networkOptions holds configuration for a NetworkWriter
+type networkOptions struct {
+	tlsConfig    *tls.Config   // Enables TLS on stream connections when set
+	bufferSize   int           // Entries waiting to be sent, oldest are dropped first
+	minBackoff   time.Duration // First reconnect delay, doubled after every failed attempt
+	maxBackoff   time.Duration // Upper bound for the reconnect delay
+	dialTimeout  time.Duration // Timeout for establishing a connection
+	writeTimeout time.Duration // Timeout for writing a single entry
+	fallback     io.Writer     // Receives the writer's own errors (default: os.Stderr)
+}
+
+type NetworkOption = func(options *networkOptions)
+
+// NetworkTLS enables TLS using the given configuration (TCP and Unix stream sockets only).
+func NetworkTLS(config *tls.Config) NetworkOption {
+	return func(options *networkOptions) {
+		options.tlsConfig = config
+	}
+}
+
+// NetworkBufferSize sets how many entries wait to be sent while the connection is down or slow.
+func NetworkBufferSize(size int) NetworkOption {
+	return func(options *networkOptions) {
+		options.bufferSize = size
+	}
+}
+
+// NetworkBackoff sets the reconnect delay range: attempts start after minBackoff
+// and the delay doubles after every failure up to maxBackoff.
+func NetworkBackoff(minBackoff, maxBackoff time.Duration) NetworkOption {
+	return func(options *networkOptions) {
+		options.minBackoff = minBackoff
+		options.maxBackoff = maxBackoff
+	}
+}
+
+// NetworkTimeouts sets the dial and per-entry write timeouts.
+func NetworkTimeouts(dial, write time.Duration) NetworkOption {
+	return func(options *networkOptions) {
+		options.dialTimeout = dial
+		options.writeTimeout = write
+	}
+}
+
+// NetworkFallback sets where the writer reports its own connection errors (default: os.Stderr, nil disables).
+func NetworkFallback(writer io.Writer) NetworkOption {
+	return func(options *networkOptions) {
+		options.fallback = writer
+	}
+}
+
+// NetworkWriter is an io.Writer sending entries over TCP, UDP or Unix sockets.
+// Unlike a raw net.Conn it survives broken connections: it reconnects with backoff
+// and buffers a bounded number of entries until the connection is back.
+// Entries are sent from a background goroutine, so a slow or unreachable server never blocks logging.
+type NetworkWriter struct {
+	network string
+	address string
+	options networkOptions
+
+	mu        sync.Mutex
+	buffer    [][]byte // Entries waiting for the sender, oldest first
+	inFlight  bool     // The sender is writing entries taken from buffer
+	connected bool
+	dropping  bool            // An overflow was already reported for the current outage
+	dropped   uint64          // Entries discarded because the buffer was full or the writer closed
+	synced    []chan struct{} // Closed once buffer is empty and nothing is in flight, see Sync
+	closed    bool
+
+	wake    chan struct{} // Signals the sender that entries were buffered
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewNetworkWriter creates a writer for network ("tcp", "udp", "unix" or "unixgram") and address.
+// If the first connection attempt fails, the writer is still returned and keeps
+// reconnecting in the background, so a log server outage never prevents startup.
+//
+// Example:
+//
+//	writer, err := zlog.NewNetworkWriter("tcp", "logs.internal:5170",
+//	    zlog.NetworkTLS(&tls.Config{ServerName: "logs.internal"}),
+//	    zlog.NetworkBufferSize(5000),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer writer.Close()
+//	zlog.SetOutputWriter(writer)
+func NewNetworkWriter(network, address string, options ...NetworkOption) (*NetworkWriter, error) {
+	opts := networkOptions{
+		bufferSize:   defaultNetworkBufferSize,
+		minBackoff:   defaultNetworkMinBackoff,
+		maxBackoff:   defaultNetworkMaxBackoff,
+		dialTimeout:  defaultNetworkDialTimeout,
+		writeTimeout: defaultNetworkWriteTimeout,
+		fallback:     os.Stderr,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	case "udp", "udp4", "udp6", "unixgram":
+		if opts.tlsConfig != nil {
+			return nil, fmt.Errorf("zlog: TLS is not supported over %s", network)
+		}
+	default:
+		return nil, fmt.Errorf("zlog: unsupported network %q", network)
+	}
+	if opts.bufferSize < 0 || opts.minBackoff <= 0 || opts.maxBackoff < opts.minBackoff {
+		return nil, errors.New("zlog: invalid network writer buffer size or backoff")
+	}
+
+	w := &NetworkWriter{
+		network: network,
+		address: address,
+		options: opts,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	conn, err := w.dial()
+	if err != nil {
+		reportWriterError(opts.fallback, "zlog/network", err, "Failed to connect to log server, retrying in background")
+	}
+	w.connected = conn != nil
+	go w.run(conn)
+	return w, nil
+}
+
+// Write buffers p as a single entry for the background sender, so Write only fails after Close.
+func (w *NetworkWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	w.bufferEntry(append([]byte(nil), p...))
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of entries discarded because the buffer was full,
+// or because they could not be sent before Close.
+func (w *NetworkWriter) Dropped() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Sync waits until the buffered entries have been sent, at most 5 seconds.
+// It is called by Fatal before the program exits.
+func (w *NetworkWriter) Sync() error {
+	w.mu.Lock()
+	if !w.inFlight && len(w.buffer) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	synced := make(chan struct{})
+	w.synced = append(w.synced, synced)
+	w.mu.Unlock()
+
+	timer := time.NewTimer(defaultNetworkSyncTimeout)
+	defer timer.Stop()
+	select {
+	case <-synced:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("zlog: network entries not sent within %v", defaultNetworkSyncTimeout)
+	}
+}
+
+// Close sends the buffered entries, waiting at most 5 seconds like Sync, then closes the connection.
+// Entries that could not be sent by then are dropped.
+func (w *NetworkWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	err := w.Sync()
+	close(w.stop)
+	<-w.stopped
+
+	w.mu.Lock()
+	if count := len(w.buffer); count > 0 {
+		w.dropped += uint64(count)
+		w.buffer = nil
+	}
+	w.mu.Unlock()
+	return err
+}
+
+func (w *NetworkWriter) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: w.options.dialTimeout}
+	if w.options.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, w.network, w.address, w.options.tlsConfig)
+	}
+	return dialer.Dial(w.network, w.address)
+}
+
+func (w *NetworkWriter) writeEntry(conn net.Conn, entry []byte) error {
+	if w.options.writeTimeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(w.options.writeTimeout))
+	}
+	_, err := conn.Write(entry)
+	return err
+}
+
+// bufferEntry keeps entry for the sender, dropping the oldest entry when full.
+// While connected one entry is always kept, so a buffer size of 0 only drops entries during outages.
+// Must be called with w.mu held.
+func (w *NetworkWriter) bufferEntry(entry []byte) {
+	limit := w.options.bufferSize
+	if w.connected {
+		limit = max(limit, 1)
+	}
+	if limit == 0 {
+		w.dropped++
+		return
+	}
+	if len(w.buffer) >= limit {
+		w.buffer[0] = nil
+		w.buffer = w.buffer[1:]
+		w.dropped++
+		if !w.dropping {
+			w.dropping = true
+			reportWriterError(w.options.fallback, "zlog/network", errors.New("buffer full"), "Log server unreachable, dropping oldest entries")
+		}
+	}
+	w.buffer = append(w.buffer, entry)
+}
+
+// run sends the buffered entries over conn, reconnecting with backoff whenever it breaks, until Close.
+// The lock is only held to take entries from the buffer, never while dialing or writing.
+func (w *NetworkWriter) run(conn net.Conn) {
+	defer close(w.stopped)
+	backoff := w.options.minBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	for {
+		if conn == nil {
+			timer.Reset(backoff)
+			select {
+			case <-w.stop:
+				return
+			case <-timer.C:
+			}
+			var err error
+			if conn, err = w.dial(); err != nil {
+				backoff = min(backoff*2, w.options.maxBackoff)
+				continue
+			}
+			backoff = w.options.minBackoff
+			w.mu.Lock()
+			w.connected, w.dropping = true, false
+			w.mu.Unlock()
+		}
+
+		entries := w.take()
+		if entries == nil {
+			_ = conn.Close()
+			return
+		}
+		for i, entry := range entries {
+			if err := w.writeEntry(conn, entry); err != nil {
+				reportWriterError(w.options.fallback, "zlog/network", err, "Lost connection to log server, buffering entries")
+				_ = conn.Close()
+				conn = nil
+				w.requeue(entries[i:])
+				break
+			}
+		}
+		if conn != nil {
+			w.requeue(nil)
+		}
+	}
+}
+
+// take waits for buffered entries and removes them from the buffer, it returns nil once the writer is closed
+func (w *NetworkWriter) take() [][]byte {
+	for {
+		w.mu.Lock()
+		if len(w.buffer) > 0 {
+			entries := w.buffer
+			w.buffer = nil
+			w.inFlight = true
+			w.mu.Unlock()
+			return entries
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-w.stop:
+			return nil
+		case <-w.wake:
+		}
+	}
+}
+
+// requeue puts the entries that could not be sent back in front of the buffer, they are older
+// than the entries buffered meanwhile, and notifies Sync if everything was sent
+func (w *NetworkWriter) requeue(entries [][]byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inFlight = false
+	if len(entries) > 0 {
+		w.connected = false
+		buffered := w.buffer
+		w.buffer = nil
+		for _, entry := range append(entries, buffered...) {
+			w.bufferEntry(entry)
+		}
+		return
+	}
+	if len(w.buffer) == 0 {
+		for _, synced := range w.synced {
+			close(synced)
+		}
+		w.synced = nil
+	}
+}
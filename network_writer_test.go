@@ -0,0 +1,300 @@
+package zlog_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// acceptLines accepts connections on listener and forwards every received line
+func acceptLines(listener net.Listener) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+	return lines
+}
+
+func expectLine(t *testing.T, lines <-chan string, contains string) {
+	t.Helper()
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, contains) {
+			t.Errorf("Expected line containing %q, got %s", contains, line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Timed out waiting for %q", contains)
+	}
+}
+
+func useNetworkWriter(t *testing.T, network, address string, options ...zlog.NetworkOption) *zlog.NetworkWriter {
+	t.Helper()
+	writer, err := zlog.NewNetworkWriter(network, address, options...)
+	if err != nil {
+		t.Fatalf("Failed to create network writer: %v", err)
+	}
+	zlog.SetOutputWriter(writer)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		_ = writer.Close()
+	})
+	return writer
+}
+
+// TestNetworkWriterTCP tests plain TCP delivery
+func TestNetworkWriterTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	lines := acceptLines(listener)
+
+	useNetworkWriter(t, "tcp", listener.Addr().String())
+	zlog.Info().Message("over tcp")
+	zlog.Error().Message("second entry")
+
+	expectLine(t, lines, `"msg":"over tcp"`)
+	expectLine(t, lines, `"msg":"second entry"`)
+}
+
+// TestNetworkWriterReconnect tests that the writer reconnects after the server drops the connection
+func TestNetworkWriterReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	firstConn := make(chan net.Conn, 1)
+	lines := make(chan string, 100)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		firstConn <- conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+
+	var fallback bytes.Buffer
+	useNetworkWriter(t, "tcp", listener.Addr().String(),
+		zlog.NetworkBackoff(5*time.Millisecond, 20*time.Millisecond),
+		zlog.NetworkFallback(&fallback),
+	)
+
+	// Drop the first connection from the server side
+	(<-firstConn).Close()
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		zlog.Info().Message("after reconnect")
+		select {
+		case line := <-lines:
+			if !strings.Contains(line, "after reconnect") {
+				t.Errorf("Unexpected line: %s", line)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	t.Fatal("Writer did not reconnect")
+}
+
+// TestNetworkWriterBuffersWhileDown tests bounded buffering before the server is available
+func TestNetworkWriterBuffersWhileDown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available on windows")
+	}
+	socketPath := filepath.Join(t.TempDir(), "logs.socket")
+
+	var fallback bytes.Buffer
+	writer := useNetworkWriter(t, "unix", socketPath,
+		zlog.NetworkBufferSize(3),
+		zlog.NetworkBackoff(5*time.Millisecond, 10*time.Millisecond),
+		zlog.NetworkFallback(&fallback),
+	)
+
+	for _, message := range []string{"one", "two", "three", "four", "five"} {
+		zlog.Info().Message(message)
+	}
+	if writer.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped entries, got %d", writer.Dropped())
+	}
+	if !strings.Contains(fallback.String(), `"segment":"zlog/network"`) {
+		t.Errorf("Expected connection error on fallback writer, got %q", fallback.String())
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	lines := acceptLines(listener)
+
+	expectLine(t, lines, `"msg":"three"`)
+	expectLine(t, lines, `"msg":"four"`)
+	expectLine(t, lines, `"msg":"five"`)
+}
+
+// TestNetworkWriterSlowServer tests that a server not reading its connection does not block logging
+func TestNetworkWriterSlowServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	reading := make(chan struct{})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				<-reading // Until then the writes fill the socket buffers and block
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	writer := useNetworkWriter(t, "tcp", listener.Addr().String(),
+		zlog.NetworkTimeouts(time.Second, 2*time.Second),
+		zlog.NetworkFallback(io.Discard),
+	)
+	payload := strings.Repeat("x", 64<<10)
+	start := time.Now()
+	for i := 0; i < 200; i++ {
+		zlog.Info().KeyValue("payload", payload).Message("large entry")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected logging not to wait for the server, took %v", elapsed)
+	}
+	close(reading)
+	if err := writer.Sync(); err != nil {
+		t.Errorf("Expected the entries to be sent once the server reads, got %v", err)
+	}
+}
+
+// TestNetworkWriterCloseSendsBuffered tests that Close sends the entries buffered while the server was down
+func TestNetworkWriterCloseSendsBuffered(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not available on windows")
+	}
+	socketPath := filepath.Join(t.TempDir(), "logs.socket")
+	writer := useNetworkWriter(t, "unix", socketPath,
+		zlog.NetworkBackoff(5*time.Millisecond, 10*time.Millisecond),
+		zlog.NetworkFallback(io.Discard),
+	)
+	zlog.Info().Message("one")
+	zlog.Info().Message("two")
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	lines := acceptLines(listener)
+
+	if err := writer.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	expectLine(t, lines, `"msg":"one"`)
+	expectLine(t, lines, `"msg":"two"`)
+	if writer.Dropped() != 0 {
+		t.Errorf("Expected no dropped entries, got %d", writer.Dropped())
+	}
+}
+
+// TestNetworkWriterFatal tests that Fatal sends the buffered entries, including the fatal entry, before exiting
+func TestNetworkWriterFatal(t *testing.T) {
+	if os.Getenv(fatalEnv) == t.Name() {
+		writer, err := zlog.NewNetworkWriter("tcp", os.Getenv("ZLOG_TEST_SERVER"))
+		if err != nil {
+			t.Fatalf("Failed to create network writer: %v", err)
+		}
+		zlog.SetOutputWriter(writer)
+		zlog.Info().Message("before")
+		zlog.Error().Fatal("giving up")
+		return
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	lines := acceptLines(listener)
+
+	runFatal(t, t.Name(), "ZLOG_TEST_SERVER="+listener.Addr().String())
+	expectLine(t, lines, `"msg":"before"`)
+	expectLine(t, lines, `"msg":"giving up"`)
+}
+
+// TestNetworkWriterTLS tests delivery over TLS
+func TestNetworkWriterTLS(t *testing.T) {
+	// Borrow the test certificate of an httptest TLS server
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	clientConfig := server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: server.TLS.Certificates})
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	lines := acceptLines(listener)
+
+	useNetworkWriter(t, "tcp", listener.Addr().String(), zlog.NetworkTLS(clientConfig))
+	zlog.Warn().Message("encrypted")
+
+	expectLine(t, lines, `"msg":"encrypted"`)
+}
+
+// TestNetworkWriterInvalidOptions tests option validation
+func TestNetworkWriterInvalidOptions(t *testing.T) {
+	if _, err := zlog.NewNetworkWriter("udp", "127.0.0.1:514", zlog.NetworkTLS(&tls.Config{})); err == nil {
+		t.Error("Expected error for TLS over UDP")
+	}
+	if _, err := zlog.NewNetworkWriter("ip", "127.0.0.1"); err == nil {
+		t.Error("Expected error for unsupported network")
+	}
+}
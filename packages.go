@@ -0,0 +1,171 @@
+package zlog
+
+import (
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// PackageLevelConfig sets the minimum level of entries logged from Go packages matching pattern,
+// without touching their call sites. Patterns follow the go command: "github.com/acme/billing"
+// matches that package only, "github.com/acme/billing/..." also matches the packages below it
+// and "..." matches every package. When several patterns match, the longest one wins.
+// Segment rules take precedence over package rules.
+//
+// The package is that of the function calling Debug(), Info(), etc. It is resolved once per
+// call site and cached, so package rules add little to the cost of an entry.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.MinLevelConfig(slog.LevelInfo),
+//	    zlog.PackageLevelConfig("github.com/acme/billing/...", slog.LevelDebug),
+//	    zlog.PackageAutoSourceConfig("github.com/acme/billing/...", true),
+//	))
+func PackageLevelConfig(pattern string, level slog.Level) Configurable {
+	return func(config *Config) {
+		config.updatePackage(pattern, func(rule *Rule) {
+			rule.Level = &level
+		})
+	}
+}
+
+// PackageAutoSourceConfig overrides AutoSourceConfig for entries logged from packages matching pattern.
+func PackageAutoSourceConfig(pattern string, autoSource bool) Configurable {
+	return func(config *Config) {
+		config.updatePackage(pattern, func(rule *Rule) {
+			rule.AutoSource = &autoSource
+		})
+	}
+}
+
+// PackageAutoCallStackConfig overrides AutoCallStackConfig for entries logged from packages matching pattern.
+func PackageAutoCallStackConfig(pattern string, autoCallStack bool) Configurable {
+	return func(config *Config) {
+		config.updatePackage(pattern, func(rule *Rule) {
+			rule.AutoCallStack = &autoCallStack
+		})
+	}
+}
+
+// PackageMaxCallStackDepthConfig overrides the automatic call stack depth for entries logged from packages matching pattern.
+func PackageMaxCallStackDepthConfig(pattern string, maxDepth int) Configurable {
+	return func(config *Config) {
+		config.updatePackage(pattern, func(rule *Rule) {
+			rule.MaxCallStackDepth = maxDepth
+		})
+	}
+}
+
+// updatePackage applies update to the rule of the given package pattern
+func (c *Config) updatePackage(pattern string, update func(rule *Rule)) {
+	if c.Packages == nil {
+		c.Packages = make(map[string]Rule)
+	}
+	rule := c.Packages[pattern]
+	update(&rule)
+	c.Packages[pattern] = rule
+}
+
+// packageRule is a Rule with its pattern split into a package path and whether it includes subpackages
+type packageRule struct {
+	path    string // "" with subtree matches every package
+	subtree bool   // Pattern ended in "/..."
+	Rule
+}
+
+func (r *packageRule) matches(pkg string) bool {
+	if !r.subtree {
+		return pkg == r.path
+	}
+	return r.path == "" || pkg == r.path || (strings.HasPrefix(pkg, r.path) && pkg[len(r.path)] == '/')
+}
+
+// compilePackageRules orders the rules most specific first, with the unset fields of every
+// rule inherited from the "/..." rules enclosing it.
+func compilePackageRules(packages map[string]Rule) []packageRule {
+	if len(packages) == 0 {
+		return nil
+	}
+	patterns := make([]string, 0, len(packages))
+	for pattern := range packages {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	type key struct {
+		path    string
+		subtree bool
+	}
+	byKey := make(map[key]int, len(patterns))
+	rules := make([]packageRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		k := key{path: strings.TrimSuffix(pattern, "/..."), subtree: strings.HasSuffix(pattern, "/...")}
+		if pattern == "..." {
+			k = key{path: "", subtree: true}
+		}
+		if index, ok := byKey[k]; ok {
+			rules[index].Rule = packages[pattern].inherit(rules[index].Rule)
+			continue
+		}
+		byKey[k] = len(rules)
+		rules = append(rules, packageRule{path: k.path, subtree: k.subtree, Rule: packages[pattern]})
+	}
+
+	// Least specific first: shorter paths, and for the same path the subtree before the exact package
+	sort.SliceStable(rules, func(i, j int) bool {
+		if len(rules[i].path) != len(rules[j].path) {
+			return len(rules[i].path) < len(rules[j].path)
+		}
+		return rules[i].subtree && !rules[j].subtree
+	})
+	for i := range rules {
+		for j := i - 1; j >= 0; j-- {
+			if rules[j].subtree && rules[j].matches(rules[i].path) {
+				rules[i].Rule = rules[i].inherit(rules[j].Rule)
+				break
+			}
+		}
+	}
+	for i, j := 0, len(rules)-1; i < j; i, j = i+1, j-1 {
+		rules[i], rules[j] = rules[j], rules[i]
+	}
+	return rules
+}
+
+// callerRule returns the package rule for the call site at pc, nil if no rule matches.
+// Results are cached per pc for the lifetime of the active configuration.
+func (a *activeConfig) callerRule(pc uintptr) *Rule {
+	if cached, ok := a.callerRules.Load(pc); ok {
+		return cached.(*Rule)
+	}
+	var rule *Rule
+	if fn := runtime.FuncForPC(pc - 1); fn != nil {
+		pkg := packageOf(fn.Name())
+		for i := range a.packages {
+			if a.packages[i].matches(pkg) {
+				rule = &a.packages[i].Rule
+				break
+			}
+		}
+	}
+	a.callerRules.Store(pc, rule)
+	return rule
+}
+
+// packageOf returns the import path of a function name as reported by the runtime,
+// e.g. "github.com/acme/billing" for "github.com/acme/billing.(*Service).Charge".
+func packageOf(funcName string) string {
+	// Type arguments of generic functions may contain slashes and dots ("pkg.F[example.com/x.T]")
+	if bracket := strings.IndexByte(funcName, '['); bracket >= 0 {
+		funcName = funcName[:bracket]
+	}
+	lastSlash := strings.LastIndexByte(funcName, '/')
+	if dot := strings.IndexByte(funcName[lastSlash+1:], '.'); dot >= 0 {
+		funcName = funcName[:lastSlash+1+dot]
+	}
+	// Dots in the last path element are escaped in symbol names ("gopkg.in/yaml%2ev3")
+	return strings.ReplaceAll(funcName, "%2e", ".")
+}
@@ -0,0 +1,135 @@
+package zlog_test
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// testPackage is the import path of this external test package as seen by the runtime
+const testPackage = "github.com/GokselKUCUKSAHIN/zlog_test"
+
+func logMessages(buf *bytes.Buffer) []string {
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if logData, err := parseLogOutput(line); err == nil {
+			messages = append(messages, logData["msg"].(string))
+		}
+	}
+	return messages
+}
+
+// TestPackageRules tests levels and auto features keyed by the caller's package
+func TestPackageRules(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelInfo),
+		zlog.PackageLevelConfig("github.com/acme/...", zlog.LevelTrace), // Not the caller
+		zlog.PackageLevelConfig(testPackage, slog.LevelDebug),
+		zlog.PackageAutoSourceConfig(testPackage, true),
+	))
+
+	// The same call sites twice, the second time from the per-pc cache
+	for i := 0; i < 2; i++ {
+		zlog.Trace().Message("trace")
+		zlog.Debug().Message("debug")
+	}
+
+	if messages := logMessages(&buf); strings.Join(messages, ",") != "debug,debug" {
+		t.Fatalf("Expected only debug entries, got %v", messages)
+	}
+	logData, _ := parseLogOutput(strings.Split(buf.String(), "\n")[0])
+	if source, _ := logData["source"].(string); !strings.Contains(source, "TestPackageRules") {
+		t.Errorf("Expected automatic source from package rule, got %q", source)
+	}
+}
+
+type genericLogger[T any] struct{}
+
+func (genericLogger[T]) log(message string) {
+	zlog.Debug().Message(message)
+}
+
+func logGeneric[T any](message string) {
+	zlog.Debug().Message(message)
+}
+
+// TestPackageRuleGeneric tests package rules for generic functions and methods
+// instantiated with type arguments from other packages
+func TestPackageRuleGeneric(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelInfo),
+		zlog.PackageLevelConfig(testPackage, slog.LevelDebug),
+	))
+
+	logGeneric[*bytes.Buffer]("function")
+	logGeneric[string]("shape")
+	genericLogger[slog.Level]{}.log("method")
+
+	if messages := logMessages(&buf); strings.Join(messages, ",") != "function,shape,method" {
+		t.Fatalf("Expected entries from generic code, got %v", messages)
+	}
+}
+
+// TestPackageRulePatterns tests subtree patterns, inheritance and precedence of segment rules
+func TestPackageRulePatterns(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.PackageLevelConfig("...", slog.LevelDebug),
+		zlog.PackageLevelConfig("github.com/GokselKUCUKSAHIN/...", slog.LevelError),
+		zlog.PackageAutoCallStackConfig(testPackage, true),                          // Level inherited from the enclosing pattern
+		zlog.PackageLevelConfig("github.com/GokselKUCUKSAHIN/zlog", slog.LevelInfo), // Exact, does not match zlog_test
+		zlog.SegmentLevelConfig("audit", slog.LevelInfo),
+		zlog.SegmentAutoCallStackConfig("audit", false),
+	))
+
+	zlog.Warn().Message("warn")
+	zlog.Error().Message("error")
+	zlog.Info().Segment("audit").Message("audit")
+
+	if messages := logMessages(&buf); strings.Join(messages, ",") != "error,audit" {
+		t.Fatalf("Expected error and audit entries, got %v", messages)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"callstack"`) {
+		t.Errorf("Expected inherited package rule to add a call stack: %s", lines[0])
+	}
+	if strings.Contains(lines[1], `"callstack"`) {
+		t.Errorf("Expected segment rule to override the package rule: %s", lines[1])
+	}
+}
+
+// TestPackageRulesFromFile tests package rules in the JSON file
+func TestPackageRulesFromFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{
+    "minLevel": "warn",
+    "packages": {
+        "github.com/acme/billing/...": {"level": "debug", "autoSource": true}
+    }
+}`)
+	config, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	rule := config.Packages["github.com/acme/billing/..."]
+	if rule.Level == nil || *rule.Level != slog.LevelDebug || rule.AutoSource == nil || !*rule.AutoSource {
+		t.Errorf("Unexpected package rule: %+v", rule)
+	}
+
+	writeConfigFile(t, configPath, `{"packages": {"github.com/acme/billing": {"level": "verbose"}}}`)
+	if _, err = zlog.LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), `unknown level "verbose" for package`) {
+		t.Errorf("Expected unknown level error, got %v", err)
+	}
+}
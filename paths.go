@@ -0,0 +1,148 @@
+package zlog
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PathMode selects how the file paths of source and call stack frames are written.
+type PathMode string
+
+const (
+	PathAbsolute PathMode = "absolute" // Paths as recorded at build time, e.g. "/home/ci/build/app/order/service.go", the default
+	PathModule   PathMode = "module"   // Relative to the main module root, e.g. "order/service.go"; other files as with PathGoPath
+	PathGoPath   PathMode = "gopath"   // Relative to GOPATH, the module cache or GOROOT, as written by go build -trimpath
+	PathBase     PathMode = "base"     // File name only, e.g. "service.go"
+)
+
+// UnmarshalText validates a path mode name, "" selects the default.
+func (m *PathMode) UnmarshalText(text []byte) error {
+	mode, err := parsePathMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+// parsePathMode validates a path mode name
+func parsePathMode(name string) (PathMode, error) {
+	switch mode := PathMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "", PathAbsolute, PathModule, PathGoPath, PathBase:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("zlog: unknown path mode %q (expected absolute, module, gopath or base)", name)
+	}
+}
+
+// PathModeConfig sets how the file paths of the source and call stack of level are written.
+// Absolute paths leak the layout of the build machine and change between builds; the other modes
+// keep the part that identifies the file. Modules are read from the build information of the binary.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.PathModeConfig(slog.LevelError, zlog.PathModule)))
+//	zlog.Error().WithSource().Message("Payment failed")
+//	// "source":"#payment.Charge @ payment/charge.go:42"                           main module
+//	// "source":"#mux.(*Router).ServeHTTP @ github.com/gorilla/mux@v1.8.1/mux.go:212" dependency
+//	// "source":"#http.HandlerFunc.ServeHTTP @ net/http/server.go:2136"             standard library
+func PathModeConfig(level slog.Level, mode PathMode) Configurable {
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.PathMode = mode
+		})
+	}
+}
+
+// buildModule is a module linked into the binary
+type buildModule struct {
+	path    string
+	version string // "" for the main module and modules replaced by a directory
+}
+
+// moduleIndex finds the module of a package from the build information of the binary
+type moduleIndex struct {
+	main        string        // Module path of the main module
+	mainPackage string        // Import path of the main package
+	modules     []buildModule // Every module, longest path first
+}
+
+// buildModules reads the modules of the binary once
+var buildModules = sync.OnceValue(func() moduleIndex {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return moduleIndex{}
+	}
+	index := moduleIndex{main: info.Main.Path, mainPackage: info.Path}
+	if info.Main.Path != "" {
+		index.modules = append(index.modules, buildModule{path: info.Main.Path})
+	}
+	for _, dep := range info.Deps {
+		module := buildModule{path: dep.Path, version: dep.Version}
+		if dep.Replace != nil {
+			module.version = dep.Replace.Version
+			if dep.Replace.Version != "" {
+				module.path = dep.Replace.Path
+			}
+		}
+		index.modules = append(index.modules, module)
+	}
+	sort.SliceStable(index.modules, func(i, j int) bool { return len(index.modules[i].path) > len(index.modules[j].path) })
+	return index
+})
+
+// lookup returns the module containing pkg
+func (index moduleIndex) lookup(pkg string) (buildModule, bool) {
+	for _, module := range index.modules {
+		if pkg == module.path || (strings.HasPrefix(pkg, module.path) && pkg[len(module.path)] == '/') {
+			return module, true
+		}
+	}
+	return buildModule{}, false
+}
+
+// trimPath returns the file of frame written in the given path mode. The directory of the package
+// inside its module is taken from the end of the file's directory, so files whose location does not
+// match their package are written unchanged.
+func trimPath(frame Frame, paths PathMode) string {
+	if paths == PathBase {
+		return path.Base(frame.File)
+	}
+	pkg := strings.TrimSuffix(frame.Package, "_test") // External test packages live in the directory of the package they test
+	if pkg == "" || frame.File == "" {
+		return frame.File
+	}
+	index := buildModules()
+	if pkg == "main" {
+		pkg = index.mainPackage
+	}
+
+	module, found := index.lookup(pkg)
+	packageDir := pkg
+	if found {
+		packageDir = strings.TrimPrefix(strings.TrimPrefix(pkg, module.path), "/")
+	}
+	dir, file := path.Split(frame.File)
+	dir = strings.TrimSuffix(dir, "/")
+	if packageDir != "" && dir != packageDir && !strings.HasSuffix(dir, "/"+packageDir) {
+		return frame.File
+	}
+	relative := path.Join(packageDir, file)
+	if !found {
+		return relative // Standard library, relative to GOROOT/src
+	}
+	isMain := module.path == index.main
+	if paths == PathModule && isMain {
+		return relative
+	}
+	prefix := module.path
+	if module.version != "" && !isMain {
+		prefix += "@" + module.version
+	}
+	return prefix + "/" + relative
+}
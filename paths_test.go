@@ -0,0 +1,105 @@
+package zlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestPathModes tests the file paths written for each path mode
+func TestPathModes(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	handler := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		zlog.Error().WithSource().WithCallStack().Message("paths")
+	})
+
+	tests := []struct {
+		mode         zlog.PathMode
+		source       string // File of the source
+		stdlibSuffix string // Suffix of the net/http frame's file
+	}{
+		{zlog.PathModule, "paths_test.go", "net/http/server.go"},
+		{zlog.PathGoPath, "github.com/GokselKUCUKSAHIN/zlog/paths_test.go", "net/http/server.go"},
+		{zlog.PathBase, "paths_test.go", "server.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			zlog.SetConfig(zlog.Configure(
+				zlog.FrameFormatConfig(slog.LevelError, zlog.FrameStructured),
+				zlog.PathModeConfig(slog.LevelError, tt.mode),
+			))
+			buf.Reset()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var entry struct {
+				Source    zlog.Frame   `json:"source"`
+				CallStack []zlog.Frame `json:"callstack"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("Failed to parse log output: %v", err)
+			}
+			if entry.Source.File != tt.source {
+				t.Errorf("Expected source file %q, got %q", tt.source, entry.Source.File)
+			}
+			for _, frame := range entry.CallStack {
+				if frame.Package != "net/http" {
+					continue
+				}
+				if !strings.HasSuffix(frame.File, tt.stdlibSuffix) || filepath.IsAbs(frame.File) {
+					t.Errorf("Expected net/http file ending in %q, got %q", tt.stdlibSuffix, frame.File)
+				}
+			}
+		})
+	}
+}
+
+// TestPathModeString tests path modes with the string frame format and the default absolute paths
+func TestPathModeString(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.Info().WithSource().Message("absolute")
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if source, _ := logData["source"].(string); !strings.Contains(source, " @ /") || !strings.Contains(source, "paths_test.go:") {
+		t.Errorf("Expected an absolute path by default, got %q", source)
+	}
+
+	zlog.SetConfig(zlog.Configure(zlog.PathModeConfig(slog.LevelInfo, zlog.PathModule)))
+	buf.Reset()
+	zlog.Info().WithSource().Message("module")
+	if logData, err = parseLogOutput(buf.String()); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if source, _ := logData["source"].(string); !strings.HasPrefix(source, "#zlog_test.TestPathModeString @ paths_test.go:") {
+		t.Errorf("Expected a module relative path, got %q", source)
+	}
+}
+
+// TestPathModeJSON tests decoding path modes from JSON configuration
+func TestPathModeJSON(t *testing.T) {
+	var config zlog.Config
+	if err := json.Unmarshal([]byte(`{"error": {"pathMode": "gopath"}}`), &config); err != nil {
+		t.Fatalf("Failed to decode config: %v", err)
+	}
+	if mode := config.Levels[slog.LevelError].PathMode; mode != zlog.PathGoPath {
+		t.Errorf("Expected gopath path mode, got %q", mode)
+	}
+	if err := json.Unmarshal([]byte(`{"error": {"pathMode": "relative"}}`), &config); err == nil {
+		t.Error("Expected an error for an unknown path mode")
+	}
+}
@@ -0,0 +1,323 @@
+package zlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces redacted values, suffixed with a keyed hash when RedactHashConfig is set
+const redactedValue = "[REDACTED]"
+
+// Built-in patterns for RedactPatternsConfig.
+const (
+	EmailPattern      = `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`
+	CardNumberPattern = `\b(?:\d[ -]?){12,18}\d\b` // Matches are redacted only if they pass the Luhn check
+	JWTPattern        = `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`
+)
+
+// DefaultRedactedKeys are the keys redacted by RedactDefaultsConfig.
+var DefaultRedactedKeys = []string{"password", "passwd", "secret", "token", "authorization", "api_key", "cookie"}
+
+// Secret wraps a value that must never be logged. It renders as "[REDACTED]" in entries, in context
+// values, with fmt and in JSON, whether or not redaction is configured. Convert it back with string(secret).
+//
+// Example:
+//
+//	ctx = context.WithValue(ctx, "session", zlog.Secret(sessionID))
+//	zlog.Info().Context(ctx, []string{"session"}).Msg("Session resumed")
+//	// Output: {"level":"INFO","msg":"Session resumed","app_ctx":{"session":"[REDACTED]"}}
+type Secret string
+
+func (s Secret) String() string               { return redactedValue }
+func (s Secret) GoString() string             { return redactedValue }
+func (s Secret) LogValue() slog.Value         { return slog.StringValue(redactedValue) }
+func (s Secret) MarshalJSON() ([]byte, error) { return json.Marshal(redactedValue) }
+func (s Secret) MarshalText() ([]byte, error) { return []byte(redactedValue), nil }
+
+// Redaction configures how sensitive values are removed from entries.
+type Redaction struct {
+	Keys     []string // Keys whose values are redacted, see RedactKeysConfig
+	Patterns []string // Regular expressions whose matches are redacted from messages and string values
+	HashKey  []byte   // HMAC-SHA256 key making redacted values joinable, see RedactHashConfig (never written to JSON)
+}
+
+// RedactKeysConfig redacts the values of attributes and context values with the given keys.
+// Keys match case-insensitively and ignoring "_", "-" and ".", as a suffix of the attribute key:
+// "token" redacts "token", "access_token" and "X-Auth-Token", but not "tokens_used".
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.RedactKeysConfig("password", "authorization")))
+//	zlog.Info().KeyValue("Authorization", header).Msg("Request received")
+//	// Output: {"level":"INFO","msg":"Request received","Authorization":"[REDACTED]"}
+func RedactKeysConfig(keys ...string) Configurable {
+	return func(config *Config) {
+		config.updateRedaction(func(redaction *Redaction) {
+			redaction.Keys = append(redaction.Keys, keys...)
+		})
+	}
+}
+
+// RedactPatternsConfig redacts the matches of the given regular expressions from messages and string values,
+// see EmailPattern, CardNumberPattern and JWTPattern. Invalid patterns are reported and ignored.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(zlog.RedactPatternsConfig(zlog.EmailPattern)))
+//	zlog.Info().Msgf("Invitation sent to %s", "jane@example.com")
+//	// Output: {"level":"INFO","msg":"Invitation sent to [REDACTED]"}
+func RedactPatternsConfig(patterns ...string) Configurable {
+	return func(config *Config) {
+		config.updateRedaction(func(redaction *Redaction) {
+			redaction.Patterns = append(redaction.Patterns, patterns...)
+		})
+	}
+}
+
+// RedactHashConfig replaces redacted values with "[REDACTED:<hash>]", the first 16 hex digits of their
+// HMAC-SHA256 under key, so equal values can still be correlated across entries. Keep the key secret:
+// low-entropy values such as emails can be recovered by anyone holding it.
+func RedactHashConfig(key []byte) Configurable {
+	return func(config *Config) {
+		config.updateRedaction(func(redaction *Redaction) {
+			redaction.HashKey = append([]byte(nil), key...)
+		})
+	}
+}
+
+// RedactDefaultsConfig redacts DefaultRedactedKeys, emails, card numbers and JWTs.
+func RedactDefaultsConfig() Configurable {
+	return func(config *Config) {
+		RedactKeysConfig(DefaultRedactedKeys...)(config)
+		RedactPatternsConfig(EmailPattern, CardNumberPattern, JWTPattern)(config)
+	}
+}
+
+// updateRedaction applies update to the redaction settings, creating them if needed
+func (c *Config) updateRedaction(update func(redaction *Redaction)) {
+	redaction := Redaction{}
+	if c.Redaction != nil {
+		redaction = c.Redaction.clone()
+	}
+	update(&redaction)
+	c.Redaction = &redaction
+}
+
+func (r Redaction) clone() Redaction {
+	r.Keys = append([]string(nil), r.Keys...)
+	r.Patterns = append([]string(nil), r.Patterns...)
+	r.HashKey = append([]byte(nil), r.HashKey...)
+	return r
+}
+
+// MarshalJSON writes the keys and patterns. The hash key is left out so configurations can be logged.
+func (r Redaction) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, 2)
+	if len(r.Keys) > 0 {
+		fields["keys"] = r.Keys
+	}
+	if len(r.Patterns) > 0 {
+		fields["patterns"] = r.Patterns
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON reads the keys, patterns and optional "hashKey" string
+func (r *Redaction) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Keys     []string `json:"keys"`
+		Patterns []string `json:"patterns"`
+		HashKey  string   `json:"hashKey"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	r.Keys, r.Patterns = fields.Keys, fields.Patterns
+	if fields.HashKey != "" {
+		r.HashKey = []byte(fields.HashKey)
+	}
+	return nil
+}
+
+// redactor applies a Redaction to the entries, see entryHandler
+type redactor struct {
+	keys     []string // Normalized, see normalizeRedactedKey
+	patterns []*regexp.Regexp
+	luhn     []bool // Whether matches of the pattern at the same index must pass the Luhn check
+	hashKey  []byte
+}
+
+// newRedactor compiles redaction, reporting invalid patterns through report
+func newRedactor(redaction *Redaction, report func(pattern string, err error)) *redactor {
+	if redaction == nil {
+		return nil
+	}
+	r := &redactor{hashKey: redaction.HashKey}
+	for _, key := range redaction.Keys {
+		if key = normalizeRedactedKey(key); key != "" {
+			r.keys = append(r.keys, key)
+		}
+	}
+	for _, pattern := range redaction.Patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			report(pattern, err)
+			continue
+		}
+		r.patterns = append(r.patterns, compiled)
+		r.luhn = append(r.luhn, pattern == CardNumberPattern)
+	}
+	return r
+}
+
+// normalizeRedactedKey lower cases key and removes the separators "_", "-" and "."
+func normalizeRedactedKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+// redactsKey reports whether the values of key are redacted
+func (r *redactor) redactsKey(key string) bool {
+	if len(r.keys) == 0 {
+		return false
+	}
+	normalized := normalizeRedactedKey(key)
+	for _, redacted := range r.keys {
+		if strings.HasSuffix(normalized, redacted) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact returns the replacement of a sensitive value
+func (r *redactor) redact(value string) string {
+	if len(r.hashKey) == 0 {
+		return redactedValue
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(value))
+	return "[REDACTED:" + hex.EncodeToString(mac.Sum(nil))[:16] + "]"
+}
+
+// scrub redacts the pattern matches in value
+func (r *redactor) scrub(value string) string {
+	for i, pattern := range r.patterns {
+		value = pattern.ReplaceAllStringFunc(value, func(match string) string {
+			if r.luhn[i] && !luhnValid(match) {
+				return match
+			}
+			return r.redact(match)
+		})
+	}
+	return value
+}
+
+// attr redacts attr, which must not be a group
+func (r *redactor) attr(attr slog.Attr) slog.Attr {
+	if r.redactsKey(attr.Key) {
+		return slog.String(attr.Key, r.redact(attr.Value.String()))
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, r.scrub(attr.Value.String()))
+	case slog.KindAny:
+		return slog.Any(attr.Key, r.redactAny(attr.Value.Any()))
+	}
+	return attr
+}
+
+// redactAny redacts the values of maps (such as Context's app_ctx) and strings nested in value
+func (r *redactor) redactAny(value any) any {
+	switch v := value.(type) {
+	case Secret:
+		return v
+	case string:
+		return r.scrub(v)
+	case error:
+		return r.scrub(v.Error())
+	case []string:
+		scrubbed := make([]string, len(v))
+		for i, item := range v {
+			scrubbed[i] = r.scrub(item)
+		}
+		return scrubbed
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, item := range v {
+			if r.redactsKey(key) {
+				redacted[key] = r.redact(fmt.Sprint(item))
+			} else {
+				redacted[key] = r.redactAny(item)
+			}
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = r.redactAny(item)
+		}
+		return redacted
+	}
+	return r.redactComposite(value)
+}
+
+// redactComposite redacts other maps, slices and structs (such as http.Header or a request struct)
+// by normalizing them through their JSON form, so that their keys and field names are redacted too
+func (r *redactor) redactComposite(value any) any {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Array, reflect.Struct, reflect.Pointer, reflect.Interface:
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return value
+		}
+	default:
+		return value
+	}
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return value
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return r.scrub(fmt.Sprint(value))
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return r.scrub(string(data))
+	}
+	return r.redactAny(normalized)
+}
+
+// luhnValid reports whether the digits of number pass the Luhn checksum of card numbers
+func luhnValid(number string) bool {
+	sum, double, digits := 0, false, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			if digit *= 2; digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
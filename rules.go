@@ -0,0 +1,75 @@
+package zlog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// Rule overrides the minimum level and automatic features for entries matching a pattern,
+// either a segment (Config.Segments) or the package of the calling code (Config.Packages).
+// Unset fields fall back to the rule of an enclosing pattern ("payment" for "payment/refund"),
+// then to the global configuration.
+type Rule struct {
+	Level             *slog.Level // Minimum level for matching entries (nil = Config.MinLevel)
+	AutoSource        *bool       // Overrides the level's autoSource when set
+	AutoCallStack     *bool       // Overrides the level's autoCallStack when set
+	MaxCallStackDepth int         // Overrides the level's depth for automatic call stacks (0 = level's depth)
+}
+
+// MarshalJSON writes the rule with its level by name, omitting unset fields
+func (r Rule) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, 4)
+	if r.Level != nil {
+		fields["level"] = strings.ToLower(levelName(*r.Level))
+	}
+	if r.AutoSource != nil {
+		fields["autoSource"] = *r.AutoSource
+	}
+	if r.AutoCallStack != nil {
+		fields["autoCallStack"] = *r.AutoCallStack
+	}
+	if r.MaxCallStackDepth != 0 {
+		fields["maxCallStackDepth"] = r.MaxCallStackDepth
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON reads a rule written by MarshalJSON, the level may be any name accepted in the config file
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Level             *string `json:"level"`
+		AutoSource        *bool   `json:"autoSource"`
+		AutoCallStack     *bool   `json:"autoCallStack"`
+		MaxCallStackDepth int     `json:"maxCallStackDepth"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields.Level != nil {
+		level, err := parseLevel(*fields.Level)
+		if err != nil {
+			return err
+		}
+		r.Level = &level
+	}
+	r.AutoSource, r.AutoCallStack, r.MaxCallStackDepth = fields.AutoSource, fields.AutoCallStack, fields.MaxCallStackDepth
+	return nil
+}
+
+// inherit returns r with its unset fields taken from parent
+func (r Rule) inherit(parent Rule) Rule {
+	if r.Level == nil {
+		r.Level = parent.Level
+	}
+	if r.AutoSource == nil {
+		r.AutoSource = parent.AutoSource
+	}
+	if r.AutoCallStack == nil {
+		r.AutoCallStack = parent.AutoCallStack
+	}
+	if r.MaxCallStackDepth == 0 {
+		r.MaxCallStackDepth = parent.MaxCallStackDepth
+	}
+	return r
+}
@@ -0,0 +1,359 @@
+package zlog
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// samplerCounters is the number of per-message counters. Messages are hashed into them,
+// so memory stays bounded however many distinct messages are logged.
+const samplerCounters = 4096
+
+// Sampling holds the policies limiting the volume of entries. An entry is written only if every
+// policy keeps it; they are checked in order: per-level rate, per message, per segment budget.
+// Sampling is applied after the level filters and before the automatic source and call stack
+// are captured, so dropped entries are cheap.
+type Sampling struct {
+	Interval        time.Duration            // Window of the per-message counters (0 = no per-message sampling)
+	First           int                      // Entries per level and message logged in every window
+	Thereafter      int                      // After First, every Thereafter-th entry is logged (0 = none)
+	Rates           map[slog.Level]float64   // Probability an entry at the level is kept (missing = 1)
+	Budgets         map[string]SegmentBudget // Entries allowed per window for segment patterns, see SegmentBudgetConfig
+	SummaryInterval time.Duration            // Minimum time between summaries of dropped entries (0 = no summary)
+}
+
+// SegmentBudget allows Count entries per Interval for the segments matching a pattern.
+type SegmentBudget struct {
+	Count    int
+	Interval time.Duration
+}
+
+// SampleMessagesConfig logs the first entries of every level and message in each interval,
+// then only every thereafter-th one.
+//
+// Example:
+//
+//	// Per second: the first 100 identical entries, then 1 in 100
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.SampleMessagesConfig(time.Second, 100, 100),
+//	    zlog.SamplingSummaryConfig(time.Minute),
+//	))
+func SampleMessagesConfig(interval time.Duration, first, thereafter int) Configurable {
+	return func(config *Config) {
+		config.updateSampling(func(sampling *Sampling) {
+			sampling.Interval, sampling.First, sampling.Thereafter = interval, first, thereafter
+		})
+	}
+}
+
+// SampleLevelConfig keeps entries at level with the given probability (0 to 1).
+func SampleLevelConfig(level slog.Level, rate float64) Configurable {
+	return func(config *Config) {
+		config.updateSampling(func(sampling *Sampling) {
+			if sampling.Rates == nil {
+				sampling.Rates = make(map[slog.Level]float64)
+			}
+			sampling.Rates[level] = rate
+		})
+	}
+}
+
+// SegmentBudgetConfig allows count entries per interval for all segments matching pattern together,
+// patterns match like in SegmentLevelConfig and the longest matching pattern is used.
+func SegmentBudgetConfig(pattern string, count int, interval time.Duration) Configurable {
+	return func(config *Config) {
+		config.updateSampling(func(sampling *Sampling) {
+			if sampling.Budgets == nil {
+				sampling.Budgets = make(map[string]SegmentBudget)
+			}
+			sampling.Budgets[pattern] = SegmentBudget{Count: count, Interval: interval}
+		})
+	}
+}
+
+// SamplingSummaryConfig reports the entries dropped by each policy at most once per interval,
+// in a Notice entry on the "zlog/sampling" segment. The summary is written when the interval has elapsed,
+// by the next entry logged or, if every entry is dropped, by a timer. It is not subject to sampling
+// or level filters.
+func SamplingSummaryConfig(interval time.Duration) Configurable {
+	return func(config *Config) {
+		config.updateSampling(func(sampling *Sampling) {
+			sampling.SummaryInterval = interval
+		})
+	}
+}
+
+// updateSampling applies update to the sampling policies, creating them if needed
+func (c *Config) updateSampling(update func(sampling *Sampling)) {
+	sampling := Sampling{}
+	if c.Sampling != nil {
+		sampling = c.Sampling.clone()
+	}
+	update(&sampling)
+	c.Sampling = &sampling
+}
+
+func (s Sampling) clone() Sampling {
+	if s.Rates != nil {
+		rates := make(map[slog.Level]float64, len(s.Rates))
+		for level, rate := range s.Rates {
+			rates[level] = rate
+		}
+		s.Rates = rates
+	}
+	if s.Budgets != nil {
+		budgets := make(map[string]SegmentBudget, len(s.Budgets))
+		for pattern, budget := range s.Budgets {
+			budgets[pattern] = budget
+		}
+		s.Budgets = budgets
+	}
+	return s
+}
+
+// MarshalJSON writes durations as strings ("1m30s") and levels by name
+func (s Sampling) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, 6)
+	if s.Interval > 0 {
+		fields["interval"] = s.Interval.String()
+		fields["first"] = s.First
+		fields["thereafter"] = s.Thereafter
+	}
+	if len(s.Rates) > 0 {
+		rates := make(map[string]float64, len(s.Rates))
+		for level, rate := range s.Rates {
+			rates[strings.ToLower(levelName(level))] = rate
+		}
+		fields["rates"] = rates
+	}
+	if len(s.Budgets) > 0 {
+		budgets := make(map[string]any, len(s.Budgets))
+		for pattern, budget := range s.Budgets {
+			budgets[pattern] = map[string]any{"count": budget.Count, "interval": budget.Interval.String()}
+		}
+		fields["budgets"] = budgets
+	}
+	if s.SummaryInterval > 0 {
+		fields["summaryInterval"] = s.SummaryInterval.String()
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON reads sampling policies written by MarshalJSON
+func (s *Sampling) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Interval   string             `json:"interval"`
+		First      int                `json:"first"`
+		Thereafter int                `json:"thereafter"`
+		Rates      map[string]float64 `json:"rates"`
+		Budgets    map[string]struct {
+			Count    int    `json:"count"`
+			Interval string `json:"interval"`
+		} `json:"budgets"`
+		SummaryInterval string `json:"summaryInterval"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var err error
+	if s.Interval, err = parseOptionalDuration(fields.Interval); err != nil {
+		return err
+	}
+	if s.SummaryInterval, err = parseOptionalDuration(fields.SummaryInterval); err != nil {
+		return err
+	}
+	s.First, s.Thereafter = fields.First, fields.Thereafter
+	for name, rate := range fields.Rates {
+		level, err := parseLevel(name)
+		if err != nil {
+			return err
+		}
+		if s.Rates == nil {
+			s.Rates = make(map[slog.Level]float64)
+		}
+		s.Rates[level] = rate
+	}
+	for pattern, budget := range fields.Budgets {
+		interval, err := parseOptionalDuration(budget.Interval)
+		if err != nil {
+			return err
+		}
+		if s.Budgets == nil {
+			s.Budgets = make(map[string]SegmentBudget)
+		}
+		s.Budgets[pattern] = SegmentBudget{Count: budget.Count, Interval: interval}
+	}
+	return nil
+}
+
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// windowCounter counts events in a fixed time window without locking
+type windowCounter struct {
+	resetAt atomic.Int64 // Unix nanoseconds at which the window ends
+	count   atomic.Uint64
+}
+
+// inc counts an event at now and returns the count in the current window, including it
+func (c *windowCounter) inc(now int64, interval time.Duration) uint64 {
+	resetAt := c.resetAt.Load()
+	if now < resetAt {
+		return c.count.Add(1)
+	}
+	if c.resetAt.CompareAndSwap(resetAt, now+int64(interval)) {
+		c.count.Store(1)
+		return 1
+	}
+	return c.count.Add(1) // Another goroutine started the window
+}
+
+// budgetRule is a SegmentBudget with its pattern reduced to a segment prefix
+type budgetRule struct {
+	pattern string
+	prefix  string
+	SegmentBudget
+	used    windowCounter
+	dropped atomic.Uint64
+}
+
+// sampler applies the Sampling policies of an active configuration
+type sampler struct {
+	Sampling
+	counters    [samplerCounters]windowCounter
+	budgets     []*budgetRule                 // Longest prefix first
+	rateDropped map[slog.Level]*atomic.Uint64 // Keys fixed at creation, so reads need no locking
+	msgDropped  atomic.Uint64
+	nextSummary atomic.Int64
+	scheduled   atomic.Bool // A timer will write the next summary, see schedule
+}
+
+func newSampler(sampling *Sampling) *sampler {
+	if sampling == nil {
+		return nil
+	}
+	s := &sampler{Sampling: sampling.clone(), rateDropped: make(map[slog.Level]*atomic.Uint64, len(sampling.Rates))}
+	for level := range sampling.Rates {
+		s.rateDropped[level] = new(atomic.Uint64)
+	}
+	for pattern, budget := range sampling.Budgets {
+		s.budgets = append(s.budgets, &budgetRule{pattern: pattern, prefix: normalizeSegmentPattern(pattern), SegmentBudget: budget})
+	}
+	sort.Slice(s.budgets, func(i, j int) bool {
+		if len(s.budgets[i].prefix) != len(s.budgets[j].prefix) {
+			return len(s.budgets[i].prefix) > len(s.budgets[j].prefix)
+		}
+		return s.budgets[i].pattern < s.budgets[j].pattern
+	})
+	s.nextSummary.Store(time.Now().Add(sampling.SummaryInterval).UnixNano())
+	return s
+}
+
+// allow reports whether an entry is kept, counting it against the policy dropping it otherwise
+func (s *sampler) allow(level slog.Level, segment, message string) bool {
+	if rate, ok := s.Rates[level]; ok && rate < 1 && (rate <= 0 || rand.Float64() >= rate) {
+		s.rateDropped[level].Add(1)
+		return false
+	}
+
+	now := time.Now().UnixNano()
+	if s.Interval > 0 {
+		count := s.counters[messageHash(level, message)%samplerCounters].inc(now, s.Interval)
+		if count > uint64(s.First) && (s.Thereafter <= 0 || (count-uint64(s.First))%uint64(s.Thereafter) != 0) {
+			s.msgDropped.Add(1)
+			return false
+		}
+	}
+
+	for _, budget := range s.budgets {
+		if budget.prefix == "" || segment == budget.prefix || (strings.HasPrefix(segment, budget.prefix) && segment[len(budget.prefix)] == '/') {
+			if budget.used.inc(now, budget.Interval) > uint64(budget.Count) {
+				budget.dropped.Add(1)
+				return false
+			}
+			break
+		}
+	}
+	return true
+}
+
+// messageHash is the FNV-1a hash of level and message, computed inline to avoid allocating
+func messageHash(level slog.Level, message string) uint32 {
+	hash := uint32(2166136261) ^ uint32(level)
+	for i := 0; i < len(message); i++ {
+		hash ^= uint32(message[i])
+		hash *= 16777619
+	}
+	return hash
+}
+
+// schedule makes sure the summary of a dropped entry is written when the interval ends,
+// even if no entry is kept until then. The summary goes to the logger active at that time.
+func (s *sampler) schedule() {
+	if s.SummaryInterval <= 0 || s.scheduled.Load() || !s.scheduled.CompareAndSwap(false, true) {
+		return
+	}
+	delay := time.Duration(s.nextSummary.Load() - time.Now().UnixNano())
+	time.AfterFunc(max(delay, 0), func() {
+		s.scheduled.Store(false)
+		active := pinnedConfig()
+		defer active.unpinOutput()
+		s.report(active.logger)
+	})
+}
+
+// report writes the summary of dropped entries if the summary interval has elapsed and anything was dropped
+func (s *sampler) report(logger *slog.Logger) {
+	if s.SummaryInterval <= 0 {
+		return
+	}
+	now := time.Now()
+	next := s.nextSummary.Load()
+	if now.UnixNano() < next || !s.nextSummary.CompareAndSwap(next, now.Add(s.SummaryInterval).UnixNano()) {
+		return
+	}
+	s.writeSummary(logger)
+}
+
+// flush writes the summary of the entries dropped since the last one right away,
+// so the counts of a sampler replaced by SetConfig are not lost
+func (s *sampler) flush(logger *slog.Logger) {
+	if s.SummaryInterval > 0 {
+		s.writeSummary(logger)
+	}
+}
+
+// writeSummary writes the entries dropped by each policy since the last summary, if any, and resets the counts
+func (s *sampler) writeSummary(logger *slog.Logger) {
+	dropped := make(map[string]uint64)
+	if count := s.msgDropped.Swap(0); count > 0 {
+		dropped["messages"] = count
+	}
+	for level, counter := range s.rateDropped {
+		if count := counter.Swap(0); count > 0 {
+			dropped["rate."+strings.ToLower(levelName(level))] = count
+		}
+	}
+	for _, budget := range s.budgets {
+		if count := budget.dropped.Swap(0); count > 0 {
+			dropped["budget."+budget.pattern] = count
+		}
+	}
+	if len(dropped) == 0 {
+		return
+	}
+	logger.LogAttrs(context.Background(), LevelNotice, "Entries dropped by sampling",
+		slog.String("segment", "zlog/sampling"),
+		slog.Any("dropped", dropped),
+	)
+}
@@ -0,0 +1,280 @@
+package zlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestSampleMessages tests that the first entries of a message are logged, then every Mth
+func TestSampleMessages(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.SampleMessagesConfig(time.Hour, 3, 5)))
+	for i := 0; i < 20; i++ {
+		zlog.Info().KeyValue("i", strconv.Itoa(i)).Message("hot path")
+	}
+	zlog.Info().Message("other message")
+	zlog.Warn().Message("hot path")
+
+	var logged []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		logData, err := parseLogOutput(line)
+		if err != nil {
+			t.Fatalf("Failed to parse log line: %v", err)
+		}
+		if logData["msg"] == "hot path" && logData["level"] == "INFO" {
+			logged = append(logged, logData["i"].(string))
+		}
+	}
+	// The 1st to 3rd entries, then the 8th, 13th and 18th
+	if expected := []string{"0", "1", "2", "7", "12", "17"}; len(logged) != len(expected) {
+		t.Fatalf("Expected entries %v, got %v", expected, logged)
+	} else {
+		for i := range expected {
+			if logged[i] != expected[i] {
+				t.Errorf("Expected entries %v, got %v", expected, logged)
+				break
+			}
+		}
+	}
+	if !strings.Contains(buf.String(), `"msg":"other message"`) || !strings.Contains(buf.String(), `"level":"WARN","msg":"hot path"`) {
+		t.Errorf("Expected other messages and levels to be counted separately, got: %s", buf.String())
+	}
+}
+
+// TestSampleLevel tests probabilistic sampling per level
+func TestSampleLevel(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.SampleLevelConfig(slog.LevelDebug, 0),
+		zlog.SampleLevelConfig(slog.LevelInfo, 1),
+	))
+	for i := 0; i < 10; i++ {
+		zlog.Debug().Message("debug")
+		zlog.Info().Message("info")
+	}
+
+	if count := strings.Count(buf.String(), `"msg":"debug"`); count != 0 {
+		t.Errorf("Expected rate 0 to drop every debug entry, got %d", count)
+	}
+	if count := strings.Count(buf.String(), `"msg":"info"`); count != 10 {
+		t.Errorf("Expected rate 1 to keep every info entry, got %d", count)
+	}
+}
+
+// TestSegmentBudget tests that a budget is shared by the segments below its pattern
+func TestSegmentBudget(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.SegmentBudgetConfig("payment/*", 4, time.Hour),
+		zlog.SegmentBudgetConfig("payment/refund", 1, time.Hour),
+	))
+	for i := 0; i < 3; i++ {
+		zlog.Info().Segment("payment", "process").Message("process")
+		zlog.Info().Segment("payment", "capture").Message("capture")
+		zlog.Info().Segment("payment", "refund").Message("refund")
+		zlog.Info().Segment("search").Message("search")
+	}
+
+	output := buf.String()
+	if count := strings.Count(output, `"segment":"payment/process"`) + strings.Count(output, `"segment":"payment/capture"`); count != 4 {
+		t.Errorf("Expected 4 payment entries, got %d", count)
+	}
+	if count := strings.Count(output, `"segment":"payment/refund"`); count != 1 {
+		t.Errorf("Expected the refund budget to allow 1 entry, got %d", count)
+	}
+	if count := strings.Count(output, `"segment":"search"`); count != 3 {
+		t.Errorf("Expected unbudgeted segments to be kept, got %d", count)
+	}
+}
+
+// TestSamplingSummary tests the entry reporting what each policy dropped
+func TestSamplingSummary(t *testing.T) {
+	var buf syncBuffer
+	zlog.SetOutputWriter(&buf)
+	defer setupTestLogger(&bytes.Buffer{})
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelWarn),
+		zlog.SampleMessagesConfig(time.Hour, 1, 0),
+		zlog.SampleLevelConfig(slog.LevelError, 0),
+		zlog.SegmentBudgetConfig("db", 0, time.Hour),
+		zlog.SamplingSummaryConfig(20*time.Millisecond),
+	))
+	for i := 0; i < 5; i++ {
+		zlog.Warn().Message("repeated")
+	}
+	zlog.Error().Message("sampled out")
+	zlog.Warn().Segment("db").Message("over budget")
+
+	time.Sleep(30 * time.Millisecond)
+	zlog.Warn().Message("after interval")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected the first entry, the summary and the last entry, got: %s", buf.String())
+	}
+	summary, err := parseLogOutput(lines[1])
+	if err != nil {
+		t.Fatalf("Failed to parse summary: %v", err)
+	}
+	if summary["level"] != "NOTICE" || summary["segment"] != "zlog/sampling" {
+		t.Errorf("Expected a notice on zlog/sampling despite the minimum level, got: %s", lines[1])
+	}
+	dropped, _ := summary["dropped"].(map[string]interface{})
+	expected := map[string]float64{"messages": 4, "rate.error": 1, "budget.db": 1}
+	if len(dropped) != len(expected) {
+		t.Errorf("Expected dropped %v, got %v", expected, dropped)
+	}
+	for policy, count := range expected {
+		if dropped[policy] != count {
+			t.Errorf("Expected %v dropped by %s, got %v", count, policy, dropped[policy])
+		}
+	}
+
+	// Nothing dropped since: no new summary
+	buf.Reset()
+	time.Sleep(30 * time.Millisecond)
+	zlog.Warn().Message("quiet")
+	if strings.Contains(buf.String(), "zlog/sampling") {
+		t.Errorf("Expected no summary without drops, got: %s", buf.String())
+	}
+}
+
+// TestSamplingSummaryAllDropped tests that the summary is written when every entry is dropped
+func TestSamplingSummaryAllDropped(t *testing.T) {
+	var buf syncBuffer
+	zlog.SetOutputWriter(&buf)
+	defer setupTestLogger(&bytes.Buffer{})
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.SampleLevelConfig(slog.LevelInfo, 0),
+		zlog.SamplingSummaryConfig(20*time.Millisecond),
+	))
+	for i := 0; i < 10; i++ {
+		zlog.Info().Message("hot path")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for buf.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	summary, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Expected a single summary, got %q", buf.String())
+	}
+	if dropped, _ := summary["dropped"].(map[string]interface{}); summary["segment"] != "zlog/sampling" || dropped["rate.info"] != float64(10) {
+		t.Errorf("Expected 10 entries dropped by rate.info, got %v", summary)
+	}
+}
+
+// TestSamplingSummaryReconfigured tests that summaries follow the output and survive SetConfig replacing the sampler
+func TestSamplingSummaryReconfigured(t *testing.T) {
+	var first, second syncBuffer
+	zlog.SetOutputWriter(&first)
+	defer setupTestLogger(&bytes.Buffer{})
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.SampleLevelConfig(slog.LevelInfo, 0),
+		zlog.SamplingSummaryConfig(20*time.Millisecond),
+	))
+	for i := 0; i < 3; i++ {
+		zlog.Info().Message("hot path")
+	}
+	zlog.SetOutputWriter(&second)
+
+	deadline := time.Now().Add(time.Second)
+	for second.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	summary, err := parseLogOutput(second.String())
+	if err != nil {
+		t.Fatalf("Expected the summary in the new output, got %q", second.String())
+	}
+	if dropped, _ := summary["dropped"].(map[string]interface{}); dropped["rate.info"] != float64(3) {
+		t.Errorf("Expected 3 entries dropped by rate.info, got %v", summary)
+	}
+	if first.String() != "" {
+		t.Errorf("Expected nothing in the old output, got %q", first.String())
+	}
+
+	// Replacing the sampler writes its counts right away
+	second.Reset()
+	zlog.Info().Message("hot path")
+	zlog.Info().Message("hot path")
+	zlog.SetConfig(zlog.Configure())
+	summary, err = parseLogOutput(second.String())
+	if err != nil {
+		t.Fatalf("Expected the summary of the replaced sampler, got %q", second.String())
+	}
+	if dropped, _ := summary["dropped"].(map[string]interface{}); dropped["rate.info"] != float64(2) {
+		t.Errorf("Expected 2 entries dropped by rate.info, got %v", summary)
+	}
+}
+
+// TestSamplingFromFile tests the sampling section of the JSON file and its round trip
+func TestSamplingFromFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{
+    "sampling": {
+        "interval": "1s",
+        "first": 100,
+        "thereafter": 50,
+        "rates": {"debug": 0.1},
+        "budgets": {"payment/*": {"count": 1000, "interval": "1m"}},
+        "summaryInterval": "30s"
+    }
+}`)
+	config, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	sampling := config.Sampling
+	if sampling == nil || sampling.Interval != time.Second || sampling.First != 100 || sampling.Thereafter != 50 ||
+		sampling.Rates[slog.LevelDebug] != 0.1 || sampling.SummaryInterval != 30*time.Second {
+		t.Fatalf("Unexpected sampling: %+v", sampling)
+	}
+	if budget := sampling.Budgets["payment/*"]; budget.Count != 1000 || budget.Interval != time.Minute {
+		t.Errorf("Unexpected budget: %+v", budget)
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	var decoded zlog.Config
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+	if decoded.Sampling == nil || decoded.Sampling.Interval != time.Second || decoded.Sampling.Budgets["payment/*"].Interval != time.Minute {
+		t.Errorf("Sampling did not round trip: %s", data)
+	}
+
+	for content, expected := range map[string]string{
+		`{"sampling": {"interval": "soon"}}`:               `sampling.interval must be a positive duration`,
+		`{"sampling": {"rates": {"debug": 2}}}`:            `sampling.rates.debug must be a number between 0 and 1`,
+		`{"sampling": {"budgets": {"db": {"count": 10}}}}`: `budget "db" needs an interval`,
+		`{"sampling": {"firts": 10}}`:                      `unknown field "firts" in sampling`,
+	} {
+		writeConfigFile(t, configPath, content)
+		if _, err = zlog.LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q for %s, got %v", expected, content, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package zlog
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// SegmentLevelConfig sets the minimum level of entries whose segment matches pattern.
+// A pattern matches its segment and every segment below it: "payment" (or "payment/*") matches
+// "payment" and "payment/process", but not "payments". "*" matches every entry.
+// When several patterns match, the longest one wins. Fatal entries are written whatever their level.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.MinLevelConfig(slog.LevelInfo),
+//	    zlog.SegmentLevelConfig("payment/*", slog.LevelDebug),
+//	    zlog.SegmentAutoCallStackConfig("payment/*", true),
+//	    zlog.SegmentLevelConfig("healthcheck", slog.LevelWarn),
+//	))
+func SegmentLevelConfig(pattern string, level slog.Level) Configurable {
+	return func(config *Config) {
+		config.updateSegment(pattern, func(rule *Rule) {
+			rule.Level = &level
+		})
+	}
+}
+
+// SegmentAutoSourceConfig overrides AutoSourceConfig for entries whose segment matches pattern.
+func SegmentAutoSourceConfig(pattern string, autoSource bool) Configurable {
+	return func(config *Config) {
+		config.updateSegment(pattern, func(rule *Rule) {
+			rule.AutoSource = &autoSource
+		})
+	}
+}
+
+// SegmentAutoCallStackConfig overrides AutoCallStackConfig for entries whose segment matches pattern.
+func SegmentAutoCallStackConfig(pattern string, autoCallStack bool) Configurable {
+	return func(config *Config) {
+		config.updateSegment(pattern, func(rule *Rule) {
+			rule.AutoCallStack = &autoCallStack
+		})
+	}
+}
+
+// SegmentMaxCallStackDepthConfig overrides the automatic call stack depth for entries whose segment matches pattern.
+func SegmentMaxCallStackDepthConfig(pattern string, maxDepth int) Configurable {
+	return func(config *Config) {
+		config.updateSegment(pattern, func(rule *Rule) {
+			rule.MaxCallStackDepth = maxDepth
+		})
+	}
+}
+
+// updateSegment applies update to the rule of the given pattern
+func (c *Config) updateSegment(pattern string, update func(rule *Rule)) {
+	if c.Segments == nil {
+		c.Segments = make(map[string]Rule)
+	}
+	rule := c.Segments[pattern]
+	update(&rule)
+	c.Segments[pattern] = rule
+}
+
+// segmentRule is a SegmentRule with its pattern reduced to a segment prefix
+type segmentRule struct {
+	prefix string // "" matches every entry
+	Rule
+}
+
+// normalizeSegmentPattern strips the optional "/*" suffix and surrounding slashes
+func normalizeSegmentPattern(pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "*" {
+		return ""
+	}
+	return strings.Trim(strings.TrimSuffix(pattern, "/*"), "/")
+}
+
+// compileSegmentRules orders the rules longest prefix first, with the unset fields of every rule
+// inherited from the rules enclosing it. Patterns reducing to the same prefix ("payment" and
+// "payment/*") are merged in pattern order.
+func compileSegmentRules(segments map[string]Rule) []segmentRule {
+	if len(segments) == 0 {
+		return nil
+	}
+	patterns := make([]string, 0, len(segments))
+	for pattern := range segments {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	byPrefix := make(map[string]int, len(patterns))
+	rules := make([]segmentRule, 0, len(patterns))
+	for _, pattern := range patterns {
+		prefix, rule := normalizeSegmentPattern(pattern), segments[pattern]
+		index, ok := byPrefix[prefix]
+		if !ok {
+			byPrefix[prefix] = len(rules)
+			rules = append(rules, segmentRule{prefix: prefix, Rule: rule})
+			continue
+		}
+		rules[index].Rule = rule.inherit(rules[index].Rule)
+	}
+
+	// Resolve unset fields from the closest enclosing rule, parents first
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].prefix) < len(rules[j].prefix) })
+	for i := range rules {
+		if parent := matchSegmentRule(reversed(rules[:i]), rules[i].prefix); parent != nil {
+			rules[i].Rule = rules[i].inherit(parent.Rule)
+		}
+	}
+	return reversed(rules)
+}
+
+// reversed returns a reversed copy of rules
+func reversed(rules []segmentRule) []segmentRule {
+	result := make([]segmentRule, len(rules))
+	for i, rule := range rules {
+		result[len(rules)-1-i] = rule
+	}
+	return result
+}
+
+// matchSegmentRule returns the rule with the longest prefix matching segment, nil if none matches
+func matchSegmentRule(rules []segmentRule, segment string) *segmentRule {
+	for i := range rules {
+		prefix := rules[i].prefix
+		if prefix == "" || segment == prefix || (strings.HasPrefix(segment, prefix) && segment[len(prefix)] == '/') {
+			return &rules[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package zlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestSegmentRules tests per-segment levels and auto features resolved by longest prefix
+func TestSegmentRules(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelInfo),
+		zlog.SegmentLevelConfig("payment/*", slog.LevelDebug),
+		zlog.SegmentAutoCallStackConfig("payment/*", true),
+		zlog.SegmentAutoCallStackConfig("payment/refund", false),
+		zlog.SegmentLevelConfig("healthcheck", slog.LevelWarn),
+	))
+
+	zlog.Debug().Segment("payment", "process").Message("payment debug")
+	zlog.Debug().Segment("payment", "refund").Message("refund debug")
+	zlog.Debug().Segment("payments").Message("other debug")
+	zlog.Debug().Message("plain debug")
+	zlog.Info().Segment("healthcheck").Message("healthcheck info")
+	zlog.Info().Segment("healthcheck", "db").Message("healthcheck db info")
+	zlog.Warn().Segment("healthcheck").Message("healthcheck warn")
+	zlog.Info().Message("plain info")
+
+	entries := map[string]map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		logData, err := parseLogOutput(line)
+		if err != nil {
+			t.Fatalf("Failed to parse log line: %v", err)
+		}
+		entries[logData["msg"].(string)] = logData
+	}
+
+	for _, msg := range []string{"payment debug", "refund debug", "healthcheck warn", "plain info"} {
+		if _, ok := entries[msg]; !ok {
+			t.Errorf("Expected %q to be logged", msg)
+		}
+	}
+	for _, msg := range []string{"other debug", "plain debug", "healthcheck info", "healthcheck db info"} {
+		if _, ok := entries[msg]; ok {
+			t.Errorf("Expected %q to be filtered", msg)
+		}
+	}
+
+	if _, ok := entries["payment debug"]["callstack"]; !ok {
+		t.Error("Expected automatic call stack for payment/process")
+	}
+	if _, ok := entries["refund debug"]["callstack"]; ok {
+		t.Error("Expected the longer payment/refund rule to disable the call stack")
+	}
+}
+
+// TestSegmentRuleAutoSource tests that automatic sources point to the caller and are not duplicated
+func TestSegmentRuleAutoSource(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.AutoSourceConfig(slog.LevelInfo, true),
+		zlog.SegmentAutoSourceConfig("metrics", false),
+	))
+
+	zlog.Info().Segment("api").Message("with source")
+	zlog.Info().Segment("metrics", "flush").Message("without source")
+	zlog.Info().WithSource().Msgf("explicit %s", "source")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got: %s", buf.String())
+	}
+	logData, _ := parseLogOutput(lines[0])
+	source, _ := logData["source"].(string)
+	if !strings.Contains(source, "TestSegmentRuleAutoSource") || !strings.Contains(source, "segments_test.go") {
+		t.Errorf("Expected source of the calling test, got %q", source)
+	}
+	if logData, _ = parseLogOutput(lines[1]); logData["source"] != nil {
+		t.Errorf("Expected metrics rule to disable the source, got %v", logData["source"])
+	}
+	if strings.Count(lines[2], `"source"`) != 1 {
+		t.Errorf("Expected a single source field, got: %s", lines[2])
+	}
+}
+
+// TestSegmentRulesFromFile tests segment rules in the JSON file and their round trip
+func TestSegmentRulesFromFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{
+    "minLevel": "info",
+    "segments": {
+        "payment/*": {"level": "debug", "autoCallStack": true, "maxCallStackDepth": 8},
+        "healthcheck": {"level": "warn", "autoSource": false}
+    }
+}`)
+	config, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	payment := config.Segments["payment/*"]
+	if payment.Level == nil || *payment.Level != slog.LevelDebug || payment.AutoCallStack == nil || !*payment.AutoCallStack || payment.MaxCallStackDepth != 8 {
+		t.Errorf("Unexpected payment rule: %+v", payment)
+	}
+	if healthcheck := config.Segments["healthcheck"]; healthcheck.AutoSource == nil || *healthcheck.AutoSource || healthcheck.AutoCallStack != nil {
+		t.Errorf("Unexpected healthcheck rule: %+v", healthcheck)
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	var decoded zlog.Config
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+	if len(decoded.Segments) != 2 || *decoded.Segments["healthcheck"].Level != slog.LevelWarn {
+		t.Errorf("Segment rules did not round trip: %s", data)
+	}
+
+	writeConfigFile(t, configPath, `{"segments": {"payment": {"levle": "debug"}}}`)
+	if _, err = zlog.LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), `unknown field "levle" in segment "payment"`) {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+}
+
+// TestSegmentRuleFatal tests that Fatal writes its entry even when the segment rule discards its level
+func TestSegmentRuleFatal(t *testing.T) {
+	if os.Getenv(fatalEnv) == t.Name() {
+		zlog.SetOutputWriter(os.Stdout)
+		zlog.SetConfig(zlog.Configure(zlog.SegmentLevelConfig("healthcheck", slog.LevelWarn)))
+		zlog.Info().Segment("healthcheck").Fatal("boom")
+		return
+	}
+
+	logData, err := parseLogOutput(runFatal(t, t.Name()))
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if logData["msg"] != "boom" || logData["level"] != "INFO" || logData["segment"] != "healthcheck" {
+		t.Errorf("Expected the fatal entry with its segment, got %v", logData)
+	}
+}
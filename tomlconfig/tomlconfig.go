@@ -0,0 +1,46 @@
+// Package tomlconfig adds TOML support to zlog's configuration files.
+// Importing it registers the ".toml" extension with zlog.RegisterConfigFormat,
+// the documents use the same keys as the JSON configuration file.
+//
+// Example:
+//
+//	import (
+//	    "github.com/GokselKUCUKSAHIN/zlog"
+//	    _ "github.com/GokselKUCUKSAHIN/zlog/tomlconfig"
+//	)
+//
+//	config, err := zlog.LoadConfig("log-config.toml")
+//
+// with log-config.toml:
+//
+//	minLevel = "info"
+//
+//	[error]
+//	autoSource = true
+//	autoCallStack = true
+package tomlconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+func init() {
+	zlog.RegisterConfigFormat(".toml", Decode)
+}
+
+// Decode converts a TOML configuration document to JSON.
+func Decode(data []byte) ([]byte, error) {
+	document := map[string]any{}
+	if err := toml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	converted, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("tomlconfig: %w", err)
+	}
+	return converted, nil
+}
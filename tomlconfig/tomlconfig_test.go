@@ -0,0 +1,66 @@
+package tomlconfig_test
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+	_ "github.com/GokselKUCUKSAHIN/zlog/tomlconfig"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestLoadTOMLConfig tests loading .toml files
+func TestLoadTOMLConfig(t *testing.T) {
+	path := writeFile(t, "log-config.toml", `
+minLevel = "info"
+output = "stderr"
+
+[error]
+autoSource = true
+autoCallStack = true
+maxCallStackDepth = 8
+`)
+	config, err := zlog.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.MinLevel == nil || *config.MinLevel != slog.LevelInfo || config.Output != "stderr" {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	expected := `{"autoSource":true,"autoCallStack":true,"maxCallStackDepth":8}`
+	if levelConf, _ := json.Marshal(config.Levels[slog.LevelError]); string(levelConf) != expected {
+		t.Errorf("Expected %s, got %s", expected, levelConf)
+	}
+}
+
+// TestLoadTOMLConfigErrors tests that TOML files are validated like JSON files
+func TestLoadTOMLConfigErrors(t *testing.T) {
+	_, err := zlog.LoadConfig(writeFile(t, "unknown.toml", "[eror]\nautoSource = true\n"))
+	var configErr *zlog.ConfigError
+	if !errors.As(err, &configErr) || !strings.Contains(err.Error(), `unknown level "eror"`) {
+		t.Fatalf("Expected unknown level error, got %v", err)
+	}
+
+	_, err = zlog.LoadConfig(writeFile(t, "type.toml", "[error]\nautoSource = \"yes\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "error.autoSource must be a boolean") {
+		t.Errorf("Expected type error, got %v", err)
+	}
+
+	_, err = zlog.LoadConfig(writeFile(t, "syntax.toml", "[error]\nautoSource = \n"))
+	if err == nil || !strings.Contains(err.Error(), "toml: line") {
+		t.Errorf("Expected TOML syntax error with line, got %v", err)
+	}
+}
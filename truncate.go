@@ -0,0 +1,203 @@
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+// truncationMark is the key holding the truncation marker of maps
+const truncationMark = "…"
+
+// SizeLimits caps the size of entries. Truncated values end with a marker such as
+// "…[truncated 12345 bytes]" and the entry gets a "truncated": true field. Zero fields are not limited.
+type SizeLimits struct {
+	MaxMessageLength    int `json:"maxMessageLength,omitempty"`    // Bytes of the message
+	MaxStringLength     int `json:"maxStringLength,omitempty"`     // Bytes of each string value, including those nested in context values
+	MaxCollectionLength int `json:"maxCollectionLength,omitempty"` // Items of each slice and map
+	MaxNestingDepth     int `json:"maxNestingDepth,omitempty"`     // Levels of nested slices and maps in a value (1 = no nesting)
+	MaxEntryBytes       int `json:"maxEntryBytes,omitempty"`       // Bytes of the whole entry, largest values are truncated first
+}
+
+// MaxMessageLengthConfig truncates messages longer than maxBytes.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.MaxMessageLengthConfig(4096),
+//	    zlog.MaxStringLengthConfig(1024),
+//	    zlog.MaxEntryBytesConfig(64*1024),
+//	))
+func MaxMessageLengthConfig(maxBytes int) Configurable {
+	return func(config *Config) {
+		config.updateSizeLimits(func(limits *SizeLimits) { limits.MaxMessageLength = maxBytes })
+	}
+}
+
+// MaxStringLengthConfig truncates string values longer than maxBytes, including those nested in context values.
+func MaxStringLengthConfig(maxBytes int) Configurable {
+	return func(config *Config) {
+		config.updateSizeLimits(func(limits *SizeLimits) { limits.MaxStringLength = maxBytes })
+	}
+}
+
+// MaxCollectionLengthConfig keeps the first maxItems of slices and maps (in key order).
+func MaxCollectionLengthConfig(maxItems int) Configurable {
+	return func(config *Config) {
+		config.updateSizeLimits(func(limits *SizeLimits) { limits.MaxCollectionLength = maxItems })
+	}
+}
+
+// MaxNestingDepthConfig replaces the slices and maps nested deeper than maxDepth in a value with a marker.
+func MaxNestingDepthConfig(maxDepth int) Configurable {
+	return func(config *Config) {
+		config.updateSizeLimits(func(limits *SizeLimits) { limits.MaxNestingDepth = maxDepth })
+	}
+}
+
+// MaxEntryBytesConfig truncates the largest values of entries longer than maxBytes until they fit,
+// dropping the last attributes if that is not enough.
+func MaxEntryBytesConfig(maxBytes int) Configurable {
+	return func(config *Config) {
+		config.updateSizeLimits(func(limits *SizeLimits) { limits.MaxEntryBytes = maxBytes })
+	}
+}
+
+// updateSizeLimits applies update to the size limits, creating them if needed
+func (c *Config) updateSizeLimits(update func(limits *SizeLimits)) {
+	limits := SizeLimits{}
+	if c.SizeLimits != nil {
+		limits = *c.SizeLimits
+	}
+	update(&limits)
+	c.SizeLimits = &limits
+}
+
+// truncator applies SizeLimits to the values of a record, remembering whether anything was truncated
+type truncator struct {
+	limits    SizeLimits
+	truncated bool
+}
+
+// limitString cuts s to maxBytes if it is longer, unless maxBytes is 0 (no limit)
+func (t *truncator) limitString(s string, maxBytes int) string {
+	if maxBytes <= 0 {
+		return s
+	}
+	return t.cutString(s, maxBytes)
+}
+
+// cutString cuts s to maxBytes on a rune boundary, followed by the marker
+func (t *truncator) cutString(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	t.truncated = true
+	return fmt.Sprintf("%s…[truncated %d bytes]", s[:cut], len(s)-cut)
+}
+
+// attr applies the string, collection and depth limits to attr
+func (t *truncator) attr(attr slog.Attr) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, t.limitString(attr.Value.String(), t.limits.MaxStringLength))
+	case slog.KindAny:
+		if t.limits.MaxStringLength > 0 || t.limits.MaxCollectionLength > 0 || t.limits.MaxNestingDepth > 0 {
+			return slog.Any(attr.Key, t.value(attr.Value.Any(), 1))
+		}
+	}
+	return attr
+}
+
+// value applies the limits to a value at the given depth, 1 being the attribute value itself.
+// Values other than strings, maps and slices are turned into their JSON form first.
+func (t *truncator) value(value any, depth int) any {
+	switch v := value.(type) {
+	case nil, bool, json.Number, float64, int, int64, uint64:
+		return v
+	case string:
+		return t.limitString(v, t.limits.MaxStringLength)
+	case error:
+		return t.limitString(v.Error(), t.limits.MaxStringLength)
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return t.slice(items, depth)
+	case []any:
+		return t.slice(v, depth)
+	case map[string]any:
+		return t.object(v, depth)
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Pointer, reflect.Interface:
+	default:
+		return value
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic any
+	if err = decoder.Decode(&generic); err != nil {
+		return value
+	}
+	return t.value(generic, depth)
+}
+
+func (t *truncator) slice(items []any, depth int) any {
+	if t.limits.MaxNestingDepth > 0 && depth > t.limits.MaxNestingDepth {
+		t.truncated = true
+		return fmt.Sprintf("…[truncated at depth %d]", depth)
+	}
+	kept, dropped := items, 0
+	if t.limits.MaxCollectionLength > 0 && len(items) > t.limits.MaxCollectionLength {
+		kept, dropped = items[:t.limits.MaxCollectionLength], len(items)-t.limits.MaxCollectionLength
+	}
+	limited := make([]any, 0, len(kept)+1)
+	for _, item := range kept {
+		limited = append(limited, t.value(item, depth+1))
+	}
+	if dropped > 0 {
+		t.truncated = true
+		limited = append(limited, fmt.Sprintf("…[truncated %d items]", dropped))
+	}
+	return limited
+}
+
+func (t *truncator) object(fields map[string]any, depth int) any {
+	if t.limits.MaxNestingDepth > 0 && depth > t.limits.MaxNestingDepth {
+		t.truncated = true
+		return fmt.Sprintf("…[truncated at depth %d]", depth)
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	dropped := 0
+	if t.limits.MaxCollectionLength > 0 && len(keys) > t.limits.MaxCollectionLength {
+		keys, dropped = keys[:t.limits.MaxCollectionLength], len(keys)-t.limits.MaxCollectionLength
+	}
+	limited := make(map[string]any, len(keys)+1)
+	for _, key := range keys {
+		limited[key] = t.value(fields[key], depth+1)
+	}
+	if dropped > 0 {
+		t.truncated = true
+		limited[truncationMark] = fmt.Sprintf("[truncated %d items]", dropped)
+	}
+	return limited
+}
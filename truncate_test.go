@@ -0,0 +1,169 @@
+package zlog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// TestTruncateMessageAndStrings tests the message and string value limits
+func TestTruncateMessageAndStrings(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MaxMessageLengthConfig(10),
+		zlog.MaxStringLengthConfig(8),
+	))
+	zlog.Info().KeyValue("payload", "ééééé").KeyValue("short", "ok").Msg("0123456789abcde")
+	zlog.Info().KeyValue("short", "ok").Msg("fits")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	logData, err := parseLogOutput(lines[0])
+	if err != nil {
+		t.Fatalf("Failed to parse log line: %v", err)
+	}
+	if logData["msg"] != "0123456789…[truncated 5 bytes]" {
+		t.Errorf("Unexpected message: %v", logData["msg"])
+	}
+	// 4 two-byte runes fit in 8 bytes, the cut never splits a rune
+	if logData["payload"] != "éééé…[truncated 2 bytes]" || logData["short"] != "ok" {
+		t.Errorf("Unexpected values: %s", lines[0])
+	}
+	if logData["truncated"] != true {
+		t.Error("Expected the truncated flag")
+	}
+	if logData, _ = parseLogOutput(lines[1]); logData["truncated"] != nil {
+		t.Errorf("Expected no flag on entries within the limits, got: %s", lines[1])
+	}
+}
+
+// TestTruncateCollectionsAndDepth tests the collection and nesting limits on context values
+func TestTruncateCollectionsAndDepth(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MaxCollectionLengthConfig(3),
+		zlog.MaxNestingDepthConfig(3),
+	))
+	type order struct {
+		ID    int      `json:"id"`
+		Items []string `json:"items"`
+	}
+	ctx := context.WithValue(context.Background(), "ids", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	ctx = context.WithValue(ctx, "order", order{ID: 7, Items: []string{"a", "b"}})
+	ctx = context.WithValue(ctx, "tree", map[string]any{"a": map[string]any{"b": map[string]any{"c": 1}}})
+	zlog.Info().Context(ctx, []string{"ids", "order", "tree"}).Msg("deep")
+
+	var logData struct {
+		AppCtx struct {
+			IDs   []any          `json:"ids"`
+			Order map[string]any `json:"order"`
+			Tree  map[string]any `json:"tree"`
+		} `json:"app_ctx"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &logData); err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	ids := logData.AppCtx.IDs
+	if len(ids) != 4 || ids[0] != float64(1) || ids[3] != "…[truncated 7 items]" {
+		t.Errorf("Unexpected ids: %v", ids)
+	}
+	if logData.AppCtx.Order["id"] != float64(7) {
+		t.Errorf("Expected structs to be kept, got %v", logData.AppCtx.Order)
+	}
+	// app_ctx is depth 1, tree 2, a 3: the map under a is replaced
+	a, _ := logData.AppCtx.Tree["a"].(map[string]any)
+	if a["b"] != "…[truncated at depth 4]" {
+		t.Errorf("Unexpected tree: %v", logData.AppCtx.Tree)
+	}
+	if !logData.Truncated {
+		t.Error("Expected the truncated flag")
+	}
+}
+
+// TestMaxEntryBytes tests that entries are shrunk to the entry limit and stay valid
+func TestMaxEntryBytes(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.MaxEntryBytesConfig(512)))
+	zlog.Info().KeyValue("payload", strings.Repeat("x", 100_000)).KeyValue("user", "jane").Msg("large payload")
+
+	many := zlog.Info()
+	for i := 0; i < 100; i++ {
+		many = many.KeyValue("key"+strconv.Itoa(i), "value")
+	}
+	many.Msg("many attributes")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if len(line)+1 > 512 {
+			t.Errorf("Expected at most 512 bytes, got %d", len(line)+1)
+		}
+		logData, err := parseLogOutput(line)
+		if err != nil {
+			t.Fatalf("Expected valid JSON, got %v: %s", err, line)
+		}
+		if logData["truncated"] != true {
+			t.Errorf("Expected the truncated flag: %s", line)
+		}
+		if logData["msg"] == "large payload" {
+			payload, _ := logData["payload"].(string)
+			if logData["user"] != "jane" || !strings.HasSuffix(payload, "bytes]") {
+				t.Errorf("Expected the largest value to be truncated: %s", line)
+			}
+		}
+	}
+}
+
+// TestTruncateAfterRedaction tests that values are redacted before being truncated
+func TestTruncateAfterRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.RedactPatternsConfig(zlog.EmailPattern),
+		zlog.MaxStringLengthConfig(20),
+	))
+	zlog.Info().KeyValue("contact", "contact: jane.doe@example.com").Msg("redacted")
+
+	logData, _ := parseLogOutput(buf.String())
+	if logData["contact"] != "contact: [REDACTED]" || logData["truncated"] != nil {
+		t.Errorf("Expected the email to be redacted, not cut: %s", buf.String())
+	}
+}
+
+// TestSizeLimitsFromFile tests the sizeLimits section of the JSON file
+func TestSizeLimitsFromFile(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{"sizeLimits": {"maxMessageLength": 4096, "maxEntryBytes": 65536}}`)
+	config, err := zlog.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.SizeLimits == nil || config.SizeLimits.MaxMessageLength != 4096 || config.SizeLimits.MaxEntryBytes != 65536 {
+		t.Fatalf("Unexpected size limits: %+v", config.SizeLimits)
+	}
+	data, _ := json.Marshal(config)
+	var decoded zlog.Config
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.SizeLimits == nil || *decoded.SizeLimits != *config.SizeLimits {
+		t.Errorf("Size limits did not round trip: %s", data)
+	}
+
+	writeConfigFile(t, configPath, `{"sizeLimits": {"maxEntryBytes": -1}}`)
+	if _, err = zlog.LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), "sizeLimits.maxEntryBytes must be a non-negative integer") {
+		t.Errorf("Expected negative limit error, got %v", err)
+	}
+}
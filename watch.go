@@ -0,0 +1,314 @@
+package zlog
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// WatchConfigFile applies the configuration file at path and keeps polling it for changes.
+// The file is read and validated like LoadConfig does, so YAML and TOML work once their package is imported.
+// An invalid change is reported and the running configuration is kept.
+// Applied changes are logged at Warn level on the "zlog/config" segment, listing what changed,
+// so the entry is written even when the new configuration raises the minimum level to Warn.
+// The file is checked every 2 seconds, see WatchConfigFileEvery for a custom interval.
+//
+// Unlike ConfigureFromJSONFile, an unreadable or invalid file at startup is returned as an error.
+//
+// Example:
+//
+//	stop, err := zlog.WatchConfigFile("/etc/orders/log-config.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer stop()
+func WatchConfigFile(path string) (stop func(), err error) {
+	return WatchConfigFileEvery(path, defaultWatchInterval)
+}
+
+// WatchConfigFileEvery is like WatchConfigFile but polls the file at the given interval.
+// Calling stop ends polling; no reload happens after it returns.
+func WatchConfigFileEvery(path string, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("zlog: watch interval must be positive, got %v", interval)
+	}
+	watcher := &configWatcher{
+		path:    path,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err = watcher.reload(true); err != nil {
+		return nil, err
+	}
+
+	go watcher.run(interval)
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(watcher.done) })
+		<-watcher.stopped
+	}, nil
+}
+
+// configWatcher polls a config file and applies it when its content changes
+type configWatcher struct {
+	path    string
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+	done    chan struct{} // Closed to stop polling
+	stopped chan struct{} // Closed once the polling goroutine has exited
+}
+
+func (w *configWatcher) run(interval time.Duration) {
+	defer close(w.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			if err := w.reload(false); err != nil {
+				Warn().Segment("zlog", "config").KeyValue("path", w.path).Err(err).Msg("Invalid zlog config file, keeping current configuration")
+			}
+		}
+	}
+}
+
+// reload reads the file if it looks modified and applies it when its content differs.
+func (w *configWatcher) reload(initial bool) error {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return err
+	}
+	if !initial && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(data)
+	// Remember the file state even if it is invalid, so a broken file is reported once
+	w.modTime, w.size = info.ModTime(), info.Size()
+	if !initial && hash == w.hash {
+		return nil
+	}
+	w.hash = hash
+
+	config, err := parseConfigFile(w.path, data)
+	if err != nil {
+		return err
+	}
+
+	changes := describeConfigChanges(*currentConfig(), config)
+	SetConfig(config)
+	if len(changes) > 0 {
+		logConfigChanges(w.path, changes)
+	}
+	return nil
+}
+
+// logConfigChanges writes the "zlog/config" entry describing an applied reload
+func logConfigChanges(path string, changes []string) {
+	Warn().Segment("zlog", "config").
+		KeyValue("path", path).
+		KeyValue("changes", strings.Join(changes, "; ")).
+		Msg("zlog configuration reloaded")
+}
+
+// describeConfigChanges lists the differences between two configurations,
+// e.g. "error.autoCallStack: false -> true"
+func describeConfigChanges(old, new Config) []string {
+	var changes []string
+	if oldMin, newMin := describeMinLevel(old.MinLevel), describeMinLevel(new.MinLevel); oldMin != newMin {
+		changes = append(changes, fmt.Sprintf("minLevel: %s -> %s", oldMin, newMin))
+	}
+	if old.Format != new.Format {
+		changes = append(changes, fmt.Sprintf("format: %s -> %s", describeString(string(old.Format)), describeString(string(new.Format))))
+	}
+	if old.Output != new.Output {
+		changes = append(changes, fmt.Sprintf("output: %s -> %s", describeString(old.Output), describeString(new.Output)))
+	}
+
+	levelSet := make(map[slog.Level]struct{}, len(old.Levels)+len(new.Levels))
+	for level := range old.Levels {
+		levelSet[level] = struct{}{}
+	}
+	for level := range new.Levels {
+		levelSet[level] = struct{}{}
+	}
+	sortedLevels := make([]slog.Level, 0, len(levelSet))
+	for level := range levelSet {
+		sortedLevels = append(sortedLevels, level)
+	}
+	sort.Slice(sortedLevels, func(i, j int) bool { return sortedLevels[i] < sortedLevels[j] })
+
+	for _, level := range sortedLevels {
+		name := strings.ToLower(levelName(level))
+		oldConf, newConf := old.level(level), new.level(level)
+		if enabled(oldConf.AutoSource) != enabled(newConf.AutoSource) {
+			changes = append(changes, fmt.Sprintf("%s.autoSource: %v -> %v", name, enabled(oldConf.AutoSource), enabled(newConf.AutoSource)))
+		}
+		if enabled(oldConf.AutoCallStack) != enabled(newConf.AutoCallStack) {
+			changes = append(changes, fmt.Sprintf("%s.autoCallStack: %v -> %v", name, enabled(oldConf.AutoCallStack), enabled(newConf.AutoCallStack)))
+		}
+		if enabled(oldConf.AutoGoroutineID) != enabled(newConf.AutoGoroutineID) {
+			changes = append(changes, fmt.Sprintf("%s.autoGoroutineID: %v -> %v", name, enabled(oldConf.AutoGoroutineID), enabled(newConf.AutoGoroutineID)))
+		}
+		if enabled(oldConf.AutoPprofLabels) != enabled(newConf.AutoPprofLabels) {
+			changes = append(changes, fmt.Sprintf("%s.autoPprofLabels: %v -> %v", name, enabled(oldConf.AutoPprofLabels), enabled(newConf.AutoPprofLabels)))
+		}
+		if oldConf.MaxCallStackDepth != newConf.MaxCallStackDepth {
+			changes = append(changes, fmt.Sprintf("%s.maxCallStackDepth: %d -> %d", name, oldConf.MaxCallStackDepth, newConf.MaxCallStackDepth))
+		}
+		if oldConf.FrameFormat != newConf.FrameFormat {
+			changes = append(changes, fmt.Sprintf("%s.frameFormat: %s -> %s", name, describeString(string(oldConf.FrameFormat)), describeString(string(newConf.FrameFormat))))
+		}
+		if oldConf.PathMode != newConf.PathMode {
+			changes = append(changes, fmt.Sprintf("%s.pathMode: %s -> %s", name, describeString(string(oldConf.PathMode)), describeString(string(newConf.PathMode))))
+		}
+	}
+
+	changes = append(changes, describeRuleChanges("segments", old.Segments, new.Segments)...)
+	changes = append(changes, describeRuleChanges("packages", old.Packages, new.Packages)...)
+	if oldSampling, newSampling := describeSampling(old.Sampling), describeSampling(new.Sampling); oldSampling != newSampling {
+		changes = append(changes, fmt.Sprintf("sampling: %s -> %s", oldSampling, newSampling))
+	}
+	if oldRateLimit, newRateLimit := describeRateLimit(old.RateLimit), describeRateLimit(new.RateLimit); oldRateLimit != newRateLimit {
+		changes = append(changes, fmt.Sprintf("rateLimit: %s -> %s", oldRateLimit, newRateLimit))
+	}
+	if old.DedupWindow != new.DedupWindow {
+		changes = append(changes, fmt.Sprintf("dedupWindow: %s -> %s", old.DedupWindow, new.DedupWindow))
+	}
+	if oldRedaction, newRedaction := describeRedaction(old.Redaction), describeRedaction(new.Redaction); oldRedaction != newRedaction {
+		changes = append(changes, fmt.Sprintf("redaction: %s -> %s", oldRedaction, newRedaction))
+	}
+	if oldLimits, newLimits := describeSizeLimits(old.SizeLimits), describeSizeLimits(new.SizeLimits); oldLimits != newLimits {
+		changes = append(changes, fmt.Sprintf("sizeLimits: %s -> %s", oldLimits, newLimits))
+	}
+	if oldFilter, newFilter := describeFrameFilter(old.FrameFilter), describeFrameFilter(new.FrameFilter); oldFilter != newFilter {
+		changes = append(changes, fmt.Sprintf("frameFilter: %s -> %s", oldFilter, newFilter))
+	}
+	if oldDump, newDump := describeGoroutineDump(old.GoroutineDump), describeGoroutineDump(new.GoroutineDump); oldDump != newDump {
+		changes = append(changes, fmt.Sprintf("goroutineDump: %s -> %s", oldDump, newDump))
+	}
+	return changes
+}
+
+// describeRuleChanges lists the rules of section that differ, e.g. `segments.payment/*: none -> {"level":"debug"}`
+func describeRuleChanges(section string, old, new map[string]Rule) []string {
+	patternSet := make(map[string]struct{}, len(old)+len(new))
+	for pattern := range old {
+		patternSet[pattern] = struct{}{}
+	}
+	for pattern := range new {
+		patternSet[pattern] = struct{}{}
+	}
+	sortedPatterns := make([]string, 0, len(patternSet))
+	for pattern := range patternSet {
+		sortedPatterns = append(sortedPatterns, pattern)
+	}
+	sort.Strings(sortedPatterns)
+
+	var changes []string
+	for _, pattern := range sortedPatterns {
+		oldRule, newRule := describeRule(old, pattern), describeRule(new, pattern)
+		if oldRule != newRule {
+			changes = append(changes, fmt.Sprintf("%s.%s: %s -> %s", section, pattern, oldRule, newRule))
+		}
+	}
+	return changes
+}
+
+// describeRule returns the JSON of the rule for pattern, "none" if there is none
+func describeRule(rules map[string]Rule, pattern string) string {
+	rule, ok := rules[pattern]
+	if !ok {
+		return "none"
+	}
+	data, _ := json.Marshal(rule)
+	return string(data)
+}
+
+// describeSampling returns the JSON of the sampling policies, "none" if there are none
+func describeSampling(sampling *Sampling) string {
+	if sampling == nil {
+		return "none"
+	}
+	data, _ := json.Marshal(sampling)
+	return string(data)
+}
+
+// describeRateLimit returns the JSON of the rate limit, "none" if there is none
+func describeRateLimit(rateLimit *RateLimit) string {
+	if rateLimit == nil {
+		return "none"
+	}
+	data, _ := json.Marshal(rateLimit)
+	return string(data)
+}
+
+// describeRedaction returns the JSON of the redaction settings, "none" if there are none.
+// A change of hash key is reported without the key.
+func describeRedaction(redaction *Redaction) string {
+	if redaction == nil {
+		return "none"
+	}
+	data, _ := json.Marshal(redaction)
+	if len(redaction.HashKey) > 0 {
+		sum := sha256.Sum256(redaction.HashKey)
+		return fmt.Sprintf("%s with hash key %x", data, sum[:4])
+	}
+	return string(data)
+}
+
+// describeSizeLimits returns the JSON of the size limits, "none" if there are none
+func describeSizeLimits(limits *SizeLimits) string {
+	if limits == nil {
+		return "none"
+	}
+	data, _ := json.Marshal(limits)
+	return string(data)
+}
+
+// describeFrameFilter returns the JSON of the frame filter, "none" if there is none
+func describeFrameFilter(filter *FrameFilter) string {
+	if filter == nil {
+		return "none"
+	}
+	data, _ := json.Marshal(filter)
+	return string(data)
+}
+
+// describeGoroutineDump returns the JSON of the goroutine dump settings, "none" if there are none
+func describeGoroutineDump(dump *GoroutineDump) string {
+	if dump == nil {
+		return "none"
+	}
+	data, _ := json.Marshal(dump)
+	return string(data)
+}
+
+func describeMinLevel(level *slog.Level) string {
+	if level == nil {
+		return "none"
+	}
+	return strings.ToLower(levelName(*level))
+}
+
+func describeString(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
@@ -0,0 +1,155 @@
+package zlog_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+)
+
+// syncBuffer is a bytes.Buffer safe for use by the watcher goroutine and the test
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	// Make sure the modification time differs even on coarse file systems
+	later := time.Now().Add(time.Duration(len(content)) * time.Second)
+	_ = os.Chtimes(path, later, later)
+}
+
+func waitFor(t *testing.T, condition func() bool, description string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if condition() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", description)
+}
+
+// TestWatchConfigFile tests that file changes are applied and reported
+func TestWatchConfigFile(t *testing.T) {
+	var buf syncBuffer
+	zlog.SetOutputWriter(&buf)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		zlog.SetConfig(zlog.Configure())
+	})
+
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{"error": {"autoSource": false}}`)
+
+	stop, err := zlog.WatchConfigFileEvery(configPath, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to watch config file: %v", err)
+	}
+	defer stop()
+
+	writeConfigFile(t, configPath, `{"minLevel": "warn", "error": {"autoSource": true, "maxCallStackDepth": 7}}`)
+	waitFor(t, func() bool { return strings.Contains(buf.String(), "zlog configuration reloaded") }, "reload entry")
+	stop()
+
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse reload entry: %v\nOutput: %s", err, buf.String())
+	}
+	if logData["segment"] != "zlog/config" || logData["level"] != "WARN" {
+		t.Errorf("Unexpected reload entry: %v", logData)
+	}
+	expected := "minLevel: none -> warn; error.autoSource: false -> true; error.maxCallStackDepth: 0 -> 7"
+	if logData["changes"] != expected {
+		t.Errorf("Expected changes %q, got %v", expected, logData["changes"])
+	}
+
+	buf.Reset()
+	zlog.Info().Message("filtered")
+	zlog.Error().Message("with source")
+	logData, err = parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Expected only the error entry, got: %s", buf.String())
+	}
+	if _, ok := logData["source"]; !ok {
+		t.Error("Expected reloaded autoSource to apply")
+	}
+}
+
+// TestWatchConfigFileInvalidChange tests that an invalid file keeps the running configuration
+func TestWatchConfigFileInvalidChange(t *testing.T) {
+	var buf syncBuffer
+	zlog.SetOutputWriter(&buf)
+	zlog.SetConfig(zlog.Configure())
+	t.Cleanup(func() {
+		zlog.SetOutputWriter(os.Stdout)
+		zlog.SetConfig(zlog.Configure())
+	})
+
+	configPath := filepath.Join(t.TempDir(), "log-config.json")
+	writeConfigFile(t, configPath, `{"error": {"autoSource": true}}`)
+
+	stop, err := zlog.WatchConfigFileEvery(configPath, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to watch config file: %v", err)
+	}
+	defer stop()
+
+	buf.Reset()
+	writeConfigFile(t, configPath, `{"error": {"maxCallStackDepth": -1}}`)
+	waitFor(t, func() bool { return strings.Contains(buf.String(), "keeping current configuration") }, "invalid config warning")
+	stop()
+
+	buf.Reset()
+	zlog.Error().Message("still has source")
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if _, ok := logData["source"]; !ok {
+		t.Error("Expected previous configuration to stay active")
+	}
+}
+
+// TestWatchConfigFileErrors tests errors reported when starting to watch
+func TestWatchConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := zlog.WatchConfigFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	writeConfigFile(t, invalidPath, `{"error": {"autoSource": "yes"}}`)
+	if _, err := zlog.WatchConfigFile(invalidPath); err == nil {
+		t.Error("Expected error for invalid file")
+	}
+}
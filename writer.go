@@ -0,0 +1,218 @@
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logEntry is a decoded zlog JSON line as seen by the output writers.
+// Writers receive exactly what the JSON handler produced, so they decode it back
+// into its well-known parts and leave everything else in attrs.
+type logEntry struct {
+	raw       []byte
+	time      string
+	level     slog.Level
+	levelName string
+	message   string
+	source    *Frame // nil if the entry has no source
+	attrs     map[string]any
+}
+
+// jsonDecodingWriter is implemented by the writers that read the entries written to them as JSON lines.
+// They are always written JSON, whatever the configured Format.
+type jsonDecodingWriter interface {
+	decodesJSON()
+}
+
+// decodeEntries splits p into lines and decodes each one as a zlog JSON entry.
+// Lines that are not JSON objects are passed through as a message-only Info entry
+// so that nothing written to a writer is silently lost.
+// It stops at the first error of fn and returns the number of bytes of p taken by the lines handled before it,
+// so that writers report a short write instead of having the entries already sent written again.
+func decodeEntries(p []byte, fn func(entry logEntry) error) (int, error) {
+	n := 0
+	for n < len(p) {
+		line, _, found := bytes.Cut(p[n:], []byte{'\n'})
+		next := n + len(line)
+		if found {
+			next++
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if err := fn(decodeEntry(line)); err != nil {
+				return n, err
+			}
+		}
+		n = next
+	}
+	return n, nil
+}
+
+func decodeEntry(line []byte) logEntry {
+	entry := logEntry{raw: line, level: slog.LevelInfo}
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	if err := decoder.Decode(&entry.attrs); err != nil || entry.attrs == nil {
+		entry.message = string(line)
+		entry.attrs = map[string]any{}
+		return entry
+	}
+
+	if value, ok := entry.attrs[slog.TimeKey].(string); ok {
+		entry.time = value
+		delete(entry.attrs, slog.TimeKey)
+	}
+	if value, ok := entry.attrs[slog.LevelKey].(string); ok {
+		entry.level = parseLevelName(value)
+		entry.levelName = value
+		delete(entry.attrs, slog.LevelKey)
+	}
+	if value, ok := entry.attrs[slog.MessageKey].(string); ok {
+		entry.message = value
+		delete(entry.attrs, slog.MessageKey)
+	}
+	if value, ok := entry.attrs["source"]; ok {
+		entry.source = decodeSource(value)
+		delete(entry.attrs, "source")
+	}
+	return entry
+}
+
+// parseLevelName converts a level name as written by the handler back to a slog.Level.
+// Unknown names fall back to Info.
+func parseLevelName(name string) slog.Level {
+	level, err := parseLevel(name)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// syslogSeverity maps a level to the RFC 5424 severity used by journald and GELF.
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= LevelCritical:
+		return 2 // crit
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= LevelNotice:
+		return 5 // notice
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// decodeSource reads a source written as a string or in the FrameStructured format, nil if it is neither
+func decodeSource(value any) *Frame {
+	switch v := value.(type) {
+	case string:
+		if function, file, line, ok := sourceParts(v); ok {
+			return &Frame{Function: function, File: file, Line: line}
+		}
+	case map[string]any:
+		frame := &Frame{}
+		frame.Function, _ = v["function"].(string)
+		frame.Package, _ = v["package"].(string)
+		frame.File, _ = v["file"].(string)
+		if line, ok := v["line"].(json.Number); ok {
+			number, _ := line.Int64()
+			frame.Line = int(number)
+		}
+		return frame
+	}
+	return nil
+}
+
+// sourceParts splits a formatted source string
+// ("#pkg.Func @ /path/file.go:42") into function, file and line.
+func sourceParts(source string) (function, file string, line int, ok bool) {
+	function, location, found := strings.Cut(strings.TrimPrefix(source, "#"), " @ ")
+	if !found {
+		return "", "", 0, false
+	}
+	separator := strings.LastIndexByte(location, ':')
+	if separator == -1 {
+		return function, location, 0, true
+	}
+	line, err := strconv.Atoi(location[separator+1:])
+	if err != nil {
+		return function, location, 0, true
+	}
+	return function, location[:separator], line, true
+}
+
+// attrString renders a decoded attribute value as a flat string.
+// Strings are used as-is, everything else is re-encoded as JSON.
+func attrString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// attrLines is like attrString but renders a list of strings or frames (such as a call stack)
+// one element per line, which is how journald and Graylog display multi-line fields.
+func attrLines(value any) string {
+	items, ok := value.([]any)
+	if !ok {
+		return attrString(value)
+	}
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			lines = append(lines, v)
+		case map[string]any:
+			frame := decodeSource(v)
+			lines = append(lines, fmt.Sprintf("%s @ %s:%d", frame.Function, frame.File, frame.Line))
+		default:
+			return attrString(value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reportWriterError writes a writer's own failure to its fallback writer in zlog's JSON format.
+// It must not go through the global loggers, which may be the failing writer itself.
+func reportWriterError(w io.Writer, segment string, err error, message string) {
+	if w == nil || err == nil {
+		return
+	}
+	data, marshalErr := json.Marshal(struct {
+		Time     string `json:"time"`
+		Level    string `json:"level"`
+		Segment  string `json:"segment"`
+		ErrorMsg string `json:"error_msg"`
+		Msg      string `json:"msg"`
+	}{
+		Time:     time.Now().Format(time.RFC3339),
+		Level:    slog.LevelError.String(),
+		Segment:  segment,
+		ErrorMsg: err.Error(),
+		Msg:      message,
+	})
+	if marshalErr != nil {
+		return
+	}
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,49 @@
+// Package yamlconfig adds YAML support to zlog's configuration files.
+// Importing it registers the ".yaml" and ".yml" extensions with zlog.RegisterConfigFormat,
+// the documents use the same keys as the JSON configuration file.
+//
+// Example:
+//
+//	import (
+//	    "github.com/GokselKUCUKSAHIN/zlog"
+//	    _ "github.com/GokselKUCUKSAHIN/zlog/yamlconfig"
+//	)
+//
+//	config, err := zlog.LoadConfig("log-config.yaml")
+//
+// with log-config.yaml:
+//
+//	minLevel: info
+//	error:
+//	  autoSource: true
+//	  autoCallStack: true
+package yamlconfig
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	zlog.RegisterConfigFormat(".yaml", Decode)
+	zlog.RegisterConfigFormat(".yml", Decode)
+}
+
+// Decode converts a YAML configuration document to JSON.
+func Decode(data []byte) ([]byte, error) {
+	var document any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return []byte("{}"), nil // Empty document
+	}
+	converted, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("yamlconfig: %w", err)
+	}
+	return converted, nil
+}
@@ -0,0 +1,74 @@
+package yamlconfig_test
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GokselKUCUKSAHIN/zlog"
+	_ "github.com/GokselKUCUKSAHIN/zlog/yamlconfig"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestLoadYAMLConfig tests loading .yaml and .yml files
+func TestLoadYAMLConfig(t *testing.T) {
+	for _, name := range []string{"log-config.yaml", "log-config.YML"} {
+		path := writeFile(t, name, `
+minLevel: info
+format: text
+error:
+  autoSource: true
+  autoCallStack: true
+  maxCallStackDepth: 8
+`)
+		config, err := zlog.LoadConfig(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", name, err)
+		}
+		if config.MinLevel == nil || *config.MinLevel != slog.LevelInfo || config.Format != zlog.FormatText {
+			t.Errorf("Unexpected config from %s: %+v", name, config)
+		}
+		expected := `{"autoSource":true,"autoCallStack":true,"maxCallStackDepth":8}`
+		if levelConf, _ := json.Marshal(config.Levels[slog.LevelError]); string(levelConf) != expected {
+			t.Errorf("Expected %s from %s, got %s", expected, name, levelConf)
+		}
+	}
+
+	if config, err := zlog.LoadConfig(writeFile(t, "empty.yaml", "")); err != nil || len(config.Levels) != 0 {
+		t.Errorf("Expected empty document to load as empty config, got %+v, %v", config, err)
+	}
+}
+
+// TestLoadYAMLConfigErrors tests that YAML files are validated like JSON files
+func TestLoadYAMLConfigErrors(t *testing.T) {
+	_, err := zlog.LoadConfig(writeFile(t, "unknown.yaml", "error:\n  autoSorce: true\n"))
+	var configErr *zlog.ConfigError
+	if !errors.As(err, &configErr) || !strings.Contains(err.Error(), `unknown field "autoSorce" in "error"`) {
+		t.Fatalf("Expected unknown field error, got %v", err)
+	}
+	if configErr.Line != 0 {
+		t.Errorf("Expected no position for converted documents, got %d:%d", configErr.Line, configErr.Column)
+	}
+
+	_, err = zlog.LoadConfig(writeFile(t, "negative.yml", "debug:\n  maxCallStackDepth: -2\n"))
+	if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("Expected range error, got %v", err)
+	}
+
+	_, err = zlog.LoadConfig(writeFile(t, "syntax.yaml", "error:\n  autoSource: true\n autoCallStack: true\n"))
+	if err == nil || !strings.Contains(err.Error(), "yaml: line") {
+		t.Errorf("Expected YAML syntax error with line, got %v", err)
+	}
+}
@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
+	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,7 +25,9 @@ type ZLogger interface {
 	WithSource() ZLogger
 	WithSourceSkip(skip int) ZLogger
 	WithCallStack() ZLogger
+	WithAllGoroutines() ZLogger
 	KeyValue(key, value string) ZLogger
+	Every(interval time.Duration) ZLogger
 	Message(message string)
 	Msg(message string)
 	Messagef(format string, args ...any)
@@ -32,144 +37,349 @@ type ZLogger interface {
 }
 
 type zlogImpl struct {
+	active            *activeConfig // Configuration snapshot taken when the entry was created, used until it is written
 	logger            *slog.Logger
-	attrs             []any
+	level             slog.Level
+	attrs             []slog.Attr
 	maxCallStackDepth int
+	frameStyle        frameStyle      // How the source and call stack of the level are written
+	frameFilter       *frameFilter    // Frames removed from call stacks, nil if none
+	ctx               context.Context // Context set with Context, read for pprof labels
+	segment           string          // Last segment set with Segment, matched against segment rules
+	packageRule       *Rule           // Rule matching the package of the caller, nil if none
+	callerPC          uintptr         // Call site of the level function, for the automatic source (0 = not captured)
+	hasSource         bool            // Source was added explicitly, skip the automatic one
+	hasCallStack      bool            // Call stack was added explicitly, skip the automatic one
+	hasGoroutines     bool            // Goroutine dump was added explicitly, skip the automatic one
+	alert             bool            // Marked with Alert
+	fatal             bool            // Written by Fatal or Fatalf
+	every             time.Duration   // Dedup window set with Every, overriding DedupConfig (0 = none)
+	output            []slog.Attr     // Attributes written, with the automatic ones, reused across entries
 }
 
-// levelConfig holds configuration for a specific log level
-type levelConfig struct {
-	AutoSource        bool `json:"autoSource"`        // Automatically add source information
-	AutoCallStack     bool `json:"autoCallStack"`     // Automatically add call stack information
-	MaxCallStackDepth int  `json:"maxCallStackDepth"` // Max call stack depth (0 = use default)
-}
-
-// logConfig holds global configuration for automatic features
-type logConfig struct {
-	Debug levelConfig `json:"debug"` // Configuration for Debug level (default MaxCallStackDepth: 20)
-	Info  levelConfig `json:"info"`  // Configuration for Info level (default MaxCallStackDepth: 5)
-	Warn  levelConfig `json:"warn"`  // Configuration for Warn level (default MaxCallStackDepth: 5)
-	Error levelConfig `json:"error"` // Configuration for Error level (default MaxCallStackDepth: 10)
-}
-
-type Configurable = func(config *logConfig)
+type Configurable = func(config *Config)
 
-func Configure(configs ...Configurable) logConfig {
-	conf := logConfig{}
+func Configure(configs ...Configurable) Config {
+	conf := Config{}
 	for _, configFunc := range configs {
 		configFunc(&conf)
 	}
 	return conf
 }
 
-func ConfigureFromJSONFile(configPath string) logConfig {
-	if !strings.HasSuffix(configPath, ".json") {
+// ConfigureFromJSONFile loads the configuration from a JSON file (".json" is appended if the path has no extension).
+// Errors are logged as a warning and an empty configuration is returned;
+// use LoadConfig to get errors reported instead.
+func ConfigureFromJSONFile(configPath string) Config {
+	if filepath.Ext(configPath) == "" {
 		configPath += ".json"
 	}
 
-	var conf logConfig
+	var conf Config
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		Warn().Segment("zlog", "ConfigureFromJSONFile").Err(err).Msgf("An error occured while reading zlog config file. Default configurations applied")
-		return logConfig{}
+		return Config{}
 	}
 
 	if err = json.Unmarshal(data, &conf); err != nil {
 		Warn().Segment("zlog", "ConfigureFromJSONFile").Err(err).Msgf("An error occured while json unmarshal zlog config file. Default configurations applied")
-		return logConfig{}
+		return Config{}
+	}
+	return conf
+}
+
+// ConfigureFromFile loads the configuration file at path in any format known to LoadConfig,
+// chosen by extension. Errors are logged as a warning and an empty configuration is returned.
+//
+// Example:
+//
+//	import _ "github.com/GokselKUCUKSAHIN/zlog/yamlconfig"
+//
+//	zlog.SetConfig(zlog.ConfigureFromFile("log-config.yaml"))
+func ConfigureFromFile(path string) Config {
+	conf, err := LoadConfig(path)
+	if err != nil {
+		Warn().Segment("zlog", "ConfigureFromFile").Err(err).Msg("An error occured while loading zlog config file. Default configurations applied")
+		return Config{}
 	}
 	return conf
 }
 
 func AutoSourceConfig(level slog.Level, autoSource bool) Configurable {
-	return func(config *logConfig) {
-		switch level {
-		case slog.LevelDebug:
-			config.Debug.AutoSource = autoSource
-		case slog.LevelInfo:
-			config.Info.AutoSource = autoSource
-		case slog.LevelWarn:
-			config.Warn.AutoSource = autoSource
-		case slog.LevelError:
-			config.Error.AutoSource = autoSource
-		}
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.AutoSource = &autoSource
+		})
 	}
 }
 
 func AutoCallStackConfig(level slog.Level, autoCallStack bool) Configurable {
-	return func(config *logConfig) {
-		switch level {
-		case slog.LevelDebug:
-			config.Debug.AutoCallStack = autoCallStack
-		case slog.LevelInfo:
-			config.Info.AutoCallStack = autoCallStack
-		case slog.LevelWarn:
-			config.Warn.AutoCallStack = autoCallStack
-		case slog.LevelError:
-			config.Error.AutoCallStack = autoCallStack
-		}
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.AutoCallStack = &autoCallStack
+		})
 	}
 }
 
 func MaxCallStackDepthConfig(level slog.Level, maxDepth int) Configurable {
-	return func(config *logConfig) {
-		switch level {
-		case slog.LevelDebug:
-			config.Debug.MaxCallStackDepth = maxDepth
-		case slog.LevelInfo:
-			config.Info.MaxCallStackDepth = maxDepth
-		case slog.LevelWarn:
-			config.Warn.MaxCallStackDepth = maxDepth
-		case slog.LevelError:
-			config.Error.MaxCallStackDepth = maxDepth
-		}
+	return func(config *Config) {
+		config.updateLevel(level, func(levelConf *LevelConfig) {
+			levelConf.MaxCallStackDepth = maxDepth
+		})
+	}
+}
+
+// MinLevelConfig discards all entries below level.
+// Debug() and friends return a no-op logger for disabled levels, so filtered calls cost almost nothing.
+func MinLevelConfig(level slog.Level) Configurable {
+	return func(config *Config) {
+		config.MinLevel = &level
+	}
+}
+
+// FormatConfig sets the output format, FormatJSON (default) or FormatText.
+// The journald, GELF and HTTP writers of this package decode the entries written to them,
+// so they always get JSON when set directly with SetOutputWriter.
+func FormatConfig(format Format) Configurable {
+	return func(config *Config) {
+		config.Format = format
+	}
+}
+
+// OutputConfig sets where entries are written: "stdout", "stderr" or the path of a file to append to.
+// The file is opened by SetConfig; if that fails a warning is logged and the current output is kept.
+func OutputConfig(output string) Configurable {
+	return func(config *Config) {
+		config.Output = output
+	}
+}
+
+// UseConfig merges an existing configuration, e.g. one loaded from a file, into the one being built,
+// field by field like Config.Merge: the fields it sets override the Configurables before it,
+// and the Configurables after it override its fields.
+//
+// Example:
+//
+//	zlog.SetConfig(zlog.Configure(
+//	    zlog.UseConfig(zlog.ConfigureFromJSONFile("log-config.json")),
+//	    zlog.ConfigureFromEnv("ZLOG"), // environment variables override the file
+//	))
+func UseConfig(base Config) Configurable {
+	return func(config *Config) {
+		*config = config.Merge(base)
 	}
 }
 
 var (
-	debugLogger  *slog.Logger
-	infoLogger   *slog.Logger
-	warnLogger   *slog.Logger
-	errorLogger  *slog.Logger
-	globalConfig logConfig
-	logOutput    io.Writer = os.Stdout // Can be overridden for testing
-
-	// Default call stack depths for each log level
+	// globalConfig is the configuration, output and logger every entry reads. It is replaced as a whole,
+	// never modified, so entries logged while SetConfig or SetOutputWriter runs see either the old or
+	// the new snapshot.
+	globalConfig atomic.Pointer[activeConfig]
+	reconfigure  sync.Mutex // Serializes the replacements of globalConfig, see swapConfig
+
+	// Default call stack depths for each log level, custom levels default to 5
 	defaultCallStackDepths = map[slog.Level]int{
+		LevelTrace:      20,
 		slog.LevelDebug: 20,
 		slog.LevelInfo:  5,
+		LevelNotice:     5,
 		slog.LevelWarn:  5,
 		slog.LevelError: 10,
+		LevelCritical:   10,
 	}
 )
 
-// initializeLoggers creates all loggers with the current logOutput
-func initializeLoggers() {
-	debugLogger = initNewSlog(slog.LevelDebug)
-	infoLogger = initNewSlog(slog.LevelInfo)
-	warnLogger = initNewSlog(slog.LevelWarn)
-	errorLogger = initNewSlog(slog.LevelError)
+func init() {
+	active := newActiveConfig(Config{})
+	active.output = os.Stdout
+	active.logger = initNewSlog(active)
+	globalConfig.Store(active)
 }
 
-func init() {
-	initializeLoggers()
+// swapConfig replaces globalConfig with the snapshot returned by update, which gets the current one
+// and must not modify it. Concurrent calls are applied one after the other. The file opened for the
+// previous Config.Output is closed once the new snapshot is in place and the entries created with the
+// previous one are written, see outputFile. The entries dropped by the previous sampler are summarized
+// with the new logger.
+func swapConfig(update func(current *activeConfig) *activeConfig) {
+	reconfigure.Lock()
+	defer reconfigure.Unlock()
+	current := globalConfig.Load()
+	next := update(current)
+	next.logger = initNewSlog(next)
+	globalConfig.Store(next)
+	if current.sampler != nil && current.sampler != next.sampler {
+		current.sampler.flush(next.logger)
+	}
+	if current.outputFile != nil && current.outputFile != next.outputFile {
+		current.outputFile.release()
+	}
 }
 
-func initNewSlog(customLevel slog.Level) *slog.Logger {
+// outputFile is a file opened for Config.Output. It is reference counted: the snapshots writing to it
+// hold one reference and every entry created with them another one until it is written, so replacing
+// the output never closes the file under an entry. Entries that are never written keep it open.
+type outputFile struct {
+	*os.File
+	refs atomic.Int64
+}
+
+func openOutputFile(path string) (*outputFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f := &outputFile{File: file}
+	f.refs.Store(1)
+	return f, nil
+}
+
+// acquire takes a reference, it returns false if the file was already closed
+func (f *outputFile) acquire() bool {
+	for {
+		refs := f.refs.Load()
+		if refs == 0 {
+			return false
+		}
+		if f.refs.CompareAndSwap(refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// release drops a reference and closes the file with the last one
+func (f *outputFile) release() {
+	if f.refs.Add(-1) == 0 {
+		_ = f.File.Close()
+	}
+}
+
+// activeConfig is the Config applied with SetConfig, with the lookups of the logging path precomputed,
+// and the output and logger it writes to
+type activeConfig struct {
+	Config
+	output        io.Writer     // Writer set with SetOutputWriter or opened for Config.Output
+	outputName    string        // Config.Output that output was opened for ("" = set with SetOutputWriter)
+	outputFile    *outputFile   // File opened for Config.Output, closed when the output changes and its entries are written
+	logger        *slog.Logger  // Logger writing to output in the configured format
+	segments      []segmentRule // Segment rules, longest prefix first
+	lowestSegment *slog.Level   // Lowest level set by a segment rule (nil = none)
+	packages      []packageRule // Package rules, most specific first
+	callerRules   *sync.Map     // Call site pc -> *Rule matching its package, see callerRule
+	sampler       *sampler      // Sampling policies and their counters (nil = keep every entry)
+	limiter       *limiter      // Rate limit and dedup state per level, segment and message
+	redactor      *redactor     // Compiled redaction (nil = none)
+	frameFilter   *frameFilter  // Compiled frame filter (nil = keep every frame)
+	ruleSource    bool          // Some segment or package rule turns the automatic source on
+}
+
+func newActiveConfig(config Config) *activeConfig {
+	active := &activeConfig{
+		Config:      config,
+		segments:    compileSegmentRules(config.Segments),
+		packages:    compilePackageRules(config.Packages),
+		callerRules: &sync.Map{},
+		sampler:     newSampler(config.Sampling),
+		limiter:     newLimiter(),
+		frameFilter: newFrameFilter(config.FrameFilter),
+		redactor: newRedactor(config.Redaction, func(pattern string, err error) {
+			Warn().Segment("zlog", "config").KeyValue("pattern", pattern).Err(err).Msg("Ignoring invalid redaction pattern")
+		}),
+	}
+	for _, rule := range active.segments {
+		if rule.Level != nil && (active.lowestSegment == nil || *rule.Level < *active.lowestSegment) {
+			active.lowestSegment = rule.Level
+		}
+		active.ruleSource = active.ruleSource || enabled(rule.AutoSource)
+	}
+	for _, rule := range active.packages {
+		active.ruleSource = active.ruleSource || enabled(rule.AutoSource)
+	}
+	return active
+}
+
+// mayLog reports whether an entry at level from a caller with the given package rule can pass
+// any segment rule. Entries passing it are checked against their segment when emitted.
+func (a *activeConfig) mayLog(level slog.Level, packageRule *Rule) bool {
+	minLevel := a.MinLevel
+	if packageRule != nil && packageRule.Level != nil {
+		minLevel = packageRule.Level
+	}
+	if minLevel == nil {
+		return true
+	}
+	return level >= *minLevel || (a.lowestSegment != nil && level >= *a.lowestSegment)
+}
+
+// currentConfig returns the active configuration snapshot. It must not be modified.
+func currentConfig() *Config {
+	return &globalConfig.Load().Config
+}
+
+// initNewSlog creates the logger of active, writing to its output
+func initNewSlog(active *activeConfig) *slog.Logger {
 	replaceAttr := func(groups []string, attr slog.Attr) slog.Attr {
+		if len(groups) > 0 {
+			return attr
+		}
 		switch attr.Key {
 		case "time":
-			return slog.String("time", attr.Value.Time().Format(time.RFC3339))
+			if attr.Value.Kind() == slog.KindTime {
+				return slog.String("time", formatTime(attr.Value.Time()))
+			}
 		case "level":
-			return slog.String("level", customLevel.String())
+			if level, ok := attr.Value.Any().(slog.Level); ok {
+				return slog.String("level", levelName(level))
+			}
 		}
 		return attr
 	}
-	jsonHandler := slog.NewJSONHandler(logOutput, &slog.HandlerOptions{
+	options := &slog.HandlerOptions{
 		AddSource:   false,
+		Level:       slog.Level(math.MinInt), // Levels are filtered by zlog, not by the handler
 		ReplaceAttr: replaceAttr,
-	})
-	return slog.New(jsonHandler)
+	}
+	_, decodesJSON := active.output.(jsonDecodingWriter)
+	newHandler := func(w io.Writer) slog.Handler {
+		if active.Format == FormatText && !decodesJSON {
+			return slog.NewTextHandler(w, options)
+		}
+		return slog.NewJSONHandler(w, options)
+	}
+	if active.redactor == nil && active.SizeLimits == nil {
+		return slog.New(newHandler(active.output))
+	}
+	handler := &entryHandler{
+		Handler:    newHandler(active.output),
+		redactor:   active.redactor,
+		newHandler: newHandler,
+		output:     active.output,
+		mu:         &sync.Mutex{},
+	}
+	if active.SizeLimits != nil {
+		handler.limits = *active.SizeLimits
+	}
+	return slog.New(handler)
+}
+
+// formattedTime caches the RFC3339 form of the current second, shared by the entries logged within it
+type formattedTime struct {
+	unix     int64
+	location *time.Location
+	text     string
+}
+
+var lastFormattedTime atomic.Pointer[formattedTime]
+
+// formatTime formats t as RFC3339, without allocating for entries logged in the same second
+func formatTime(t time.Time) string {
+	if cached := lastFormattedTime.Load(); cached != nil && cached.unix == t.Unix() && cached.location == t.Location() {
+		return cached.text
+	}
+	formatted := &formattedTime{unix: t.Unix(), location: t.Location(), text: t.Format(time.RFC3339)}
+	lastFormattedTime.Store(formatted)
+	return formatted.text
 }
 
 // SetConfig configures global auto-features for all loggers.
@@ -189,13 +399,57 @@ func initNewSlog(customLevel slog.Level) *slog.Logger {
 //	zlog.MaxCallStackDepthConfig(slog.LevelDebug, 12),
 //
 // ))
-func SetConfig(config logConfig) {
-	globalConfig = config
+//
+// SetConfig is safe to call while other goroutines log: each entry is written with the configuration, output
+// and logger active when it was created (by Info, Error, ...), never a mix of the old and the new ones.
+func SetConfig(config Config) {
+	config = config.clone()
+	swapConfig(func(current *activeConfig) *activeConfig {
+		next := newActiveConfig(config)
+		next.output, next.outputName, next.outputFile = current.output, current.outputName, current.outputFile
+		next.applyConfiguredOutput(config.Output)
+		return next
+	})
+}
+
+// applyConfiguredOutput switches the output to Config.Output unless it is empty or already applied
+func (a *activeConfig) applyConfiguredOutput(output string) {
+	if output == "" || output == a.outputName {
+		return
+	}
+	var writer io.Writer
+	var file *outputFile
+	switch output {
+	case "stdout":
+		writer = os.Stdout
+	case "stderr":
+		writer = os.Stderr
+	default:
+		var err error
+		if file, err = openOutputFile(output); err != nil {
+			Warn().Segment("zlog", "config").KeyValue("output", output).Err(err).Msg("Failed to open log output, keeping current output")
+			return
+		}
+		writer = file
+	}
+	a.output, a.outputName, a.outputFile = writer, output, file
+}
+
+// CurrentConfig returns a copy of the active configuration.
+//
+// Example:
+//
+//	config := zlog.CurrentConfig()
+//	data, _ := json.Marshal(config) // {"error":{"autoSource":true}}
+func CurrentConfig() Config {
+	return currentConfig().clone()
 }
 
 // SetOutputWriter sets the output writer for all loggers.
 // This allows redirecting log output to files, network connections, or any io.Writer.
 // By default, logs are written to os.Stdout.
+// A later SetConfig with an Output replaces the writer.
+// For network destinations prefer NewNetworkWriter, which reconnects instead of failing on a broken connection.
 //
 // Example:
 //
@@ -210,9 +464,32 @@ func SetConfig(config logConfig) {
 //	// Write to multiple destinations
 //	multiWriter := io.MultiWriter(os.Stdout, file)
 //	zlog.SetOutputWriter(multiWriter)
+//
+// Like SetConfig, it is safe to call while other goroutines log.
 func SetOutputWriter(writer io.Writer) {
-	logOutput = writer
-	initializeLoggers()
+	swapConfig(func(current *activeConfig) *activeConfig {
+		return current.withOutput(writer)
+	})
+}
+
+// withOutput returns a copy of a writing to writer, sharing its compiled rules and sampling,
+// rate limit and dedup state
+func (a *activeConfig) withOutput(writer io.Writer) *activeConfig {
+	next := *a
+	next.output, next.outputName, next.outputFile = writer, "", nil
+	return &next
+}
+
+// Trace returns a new logger instance at Trace level.
+// Trace level is used for very fine-grained tracing, below Debug.
+// The call stack depth is configurable (default: 20) for comprehensive debugging.
+//
+// Example:
+//
+//	Trace().KeyValue("row", "42").Message("Row decoded")
+//	// Output: {"time":"2024-03-07T10:00:00Z","level":"TRACE","row":"42","msg":"Row decoded"}
+func Trace() ZLogger {
+	return newLogger(LevelTrace)
 }
 
 // Debug returns a new logger instance at Debug level.
@@ -224,12 +501,7 @@ func SetOutputWriter(writer io.Writer) {
 //	Debug().Message("Processing item details")
 //	// Output: {"level":"debug","time":"2024-03-07T10:00:00Z","message":"Processing item details"}
 func Debug() ZLogger {
-	level := slog.LevelDebug
-	z := &zlogImpl{
-		logger:            debugLogger,
-		maxCallStackDepth: getMaxCallStackDepth(level),
-	}
-	return z.applyAutoFeatures(level)
+	return newLogger(slog.LevelDebug)
 }
 
 // Info returns a new logger instance at Info level.
@@ -241,12 +513,19 @@ func Debug() ZLogger {
 //	Info().Message("Application started successfully")
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","message":"Application started successfully"}
 func Info() ZLogger {
-	level := slog.LevelInfo
-	z := &zlogImpl{
-		logger:            infoLogger,
-		maxCallStackDepth: getMaxCallStackDepth(level),
-	}
-	return z.applyAutoFeatures(level)
+	return newLogger(slog.LevelInfo)
+}
+
+// Notice returns a new logger instance at Notice level.
+// Notice level is used for normal but significant events, between Info and Warn.
+// The call stack depth is configurable (default: 5) for basic tracing.
+//
+// Example:
+//
+//	Notice().Message("Configuration reloaded")
+//	// Output: {"time":"2024-03-07T10:00:00Z","level":"NOTICE","msg":"Configuration reloaded"}
+func Notice() ZLogger {
+	return newLogger(LevelNotice)
 }
 
 // Warn returns a new logger instance at Warn level.
@@ -258,12 +537,7 @@ func Info() ZLogger {
 //	Warn().Message("High memory usage detected")
 //	// Output: {"level":"warn","time":"2024-03-07T10:00:00Z","message":"High memory usage detected"}
 func Warn() ZLogger {
-	level := slog.LevelWarn
-	z := &zlogImpl{
-		logger:            warnLogger,
-		maxCallStackDepth: getMaxCallStackDepth(level),
-	}
-	return z.applyAutoFeatures(level)
+	return newLogger(slog.LevelWarn)
 }
 
 // Error returns a new logger instance at Error level.
@@ -275,12 +549,95 @@ func Warn() ZLogger {
 //	Error().Error(err).Message("Failed to process request")
 //	// Output: {"level":"error","time":"2024-03-07T10:00:00Z","error_msg":"connection refused","message":"Failed to process request"}
 func Error() ZLogger {
-	level := slog.LevelError
-	z := &zlogImpl{
-		logger:            errorLogger,
-		maxCallStackDepth: getMaxCallStackDepth(level),
+	return newLogger(slog.LevelError)
+}
+
+// Critical returns a new logger instance at Critical level.
+// Critical level is used for conditions that need immediate action, above Error.
+// The call stack depth is configurable (default: 10) for detailed error tracing.
+//
+// Example:
+//
+//	Critical().Err(err).Message("Primary database unreachable")
+//	// Output: {"time":"2024-03-07T10:00:00Z","level":"CRITICAL","error_msg":"connection refused","msg":"Primary database unreachable"}
+func Critical() ZLogger {
+	return newLogger(LevelCritical)
+}
+
+// Log returns a new logger instance at an arbitrary level, typically one added with RegisterLevel.
+// The call stack depth is configurable (default: 5, or the default of the matching built-in level).
+//
+// Example:
+//
+//	Log(LevelAudit).KeyValue("user", "admin").Message("Permissions changed")
+//	// Output: {"time":"2024-03-07T10:00:00Z","level":"AUDIT","user":"admin","msg":"Permissions changed"}
+func Log(level slog.Level) ZLogger {
+	return newLogger(level)
+}
+
+// newLoggerCallerSkip is the runtime.Callers skip of the code calling an entry point, seen from newLogger:
+// runtime.Callers, newLogger, the entry point (Debug, Info, ...), the caller.
+const newLoggerCallerSkip = 3
+
+// newLogger returns a logger for an entry at level, or a no-op logger if no rule can let it through.
+// It must be called directly by the entry points, see newLoggerCallerSkip.
+func newLogger(level slog.Level) ZLogger {
+	for {
+		active := globalConfig.Load()
+		var packageRule *Rule
+		var pcs [1]uintptr
+		if len(active.packages) > 0 && runtime.Callers(newLoggerCallerSkip, pcs[:]) > 0 {
+			packageRule = active.callerRule(pcs[0])
+		}
+		if !active.mayLog(level, packageRule) {
+			return newDisabledLogger(level)
+		}
+		if pcs[0] == 0 && (active.ruleSource || enabled(active.level(level).AutoSource)) {
+			// Captured here rather than when written, so the source is the level function call
+			// even if the entry is finished in a helper
+			runtime.Callers(newLoggerCallerSkip, pcs[:])
+		}
+		if active.pinOutput() {
+			z := newEntry(active, level, packageRule)
+			z.callerPC = pcs[0]
+			return z
+		}
+		// The snapshot was replaced and its output file closed in the meantime, use the new one
+	}
+}
+
+// pinnedConfig returns the current snapshot with its output pinned
+func pinnedConfig() *activeConfig {
+	for {
+		if active := globalConfig.Load(); active.pinOutput() {
+			return active
+		}
 	}
-	return z.applyAutoFeatures(level)
+}
+
+// pinOutput keeps the output file of a open until unpinOutput is called. It returns false if a was
+// replaced and its file closed already.
+func (a *activeConfig) pinOutput() bool {
+	return a.outputFile == nil || a.outputFile.acquire()
+}
+
+func (a *activeConfig) unpinOutput() {
+	if a.outputFile != nil {
+		a.outputFile.release()
+	}
+}
+
+// newEntry returns a pooled entry at level using the configuration snapshot active, whose output must be pinned
+func newEntry(active *activeConfig, level slog.Level, packageRule *Rule) *zlogImpl {
+	z := entryPool.Get().(*zlogImpl)
+	z.active = active
+	z.logger = active.logger
+	z.level = level
+	z.maxCallStackDepth = active.maxCallStackDepth(level)
+	z.frameStyle = active.level(level).frameStyle()
+	z.frameFilter = active.frameFilter
+	z.packageRule = packageRule
+	return z
 }
 
 // Panic immediately panics with the given message.
@@ -316,6 +673,7 @@ func Panicf(format string, args ...any) {
 //	Info().Context(ctx, []string{"userID", "requestID", "nonexistent"}).Message("User action")
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","app_ctx":{"userID":"12345","requestID":"req-abc-123"},"message":"User action"}
 func (z *zlogImpl) Context(ctx context.Context, keys []string) ZLogger {
+	z.ctx = ctx
 	contextMap := make(map[string]any, len(keys))
 	for _, key := range keys {
 		value := ctx.Value(key)
@@ -361,6 +719,7 @@ func (z *zlogImpl) Segment(mainSegment string, detail ...string) ZLogger {
 			mainSegment += "/" + strings.Join(validDetails, "/")
 		}
 	}
+	z.segment = mainSegment
 	return z.appendAttr(slog.String("segment", mainSegment))
 }
 
@@ -405,11 +764,12 @@ func (z *zlogImpl) Err(err error) ZLogger {
 //	Info().WithSource().Message("Processing payment")
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","source":"payment.ProcessTransaction @ /app/payment.go:42","message":"Processing payment"}
 func (z *zlogImpl) WithSource() ZLogger {
-	source, ok := getSourceString(2)
+	source, ok := callerPC(2)
 	if !ok {
 		return z
 	}
-	return z.appendAttr(slog.String("source", source))
+	z.hasSource = true
+	return z.appendAttr(sourceAttr(source, z.frameStyle))
 }
 
 // WithSourceSkip adds the caller's information to the log entry.
@@ -421,11 +781,12 @@ func (z *zlogImpl) WithSource() ZLogger {
 //	Info().WithSourceSkip(3).Message("Processing payment")
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","source":"payment.ProcessTransaction @ /app/payment.go:42","message":"Processing payment"}
 func (z *zlogImpl) WithSourceSkip(skip int) ZLogger {
-	source, ok := getSourceString(2 + skip)
+	source, ok := callerPC(2 + skip)
 	if !ok {
 		return z
 	}
-	return z.appendAttr(slog.String("source", source))
+	z.hasSource = true
+	return z.appendAttr(sourceAttr(source, z.frameStyle))
 }
 
 // WithCallStack adds the call stack information to the log entry.
@@ -438,18 +799,24 @@ func (z *zlogImpl) WithSourceSkip(skip int) ZLogger {
 //	Error().WithCallStack().Message("Unexpected error")
 //	// Output: {"level":"error","time":"2024-03-07T10:00:00Z","callstack":["app.ProcessOrder @ /app/order.go:42","app.HandleRequest @ /app/handler.go:123","main.main @ /app/main.go:15"],"message":"Unexpected error"}
 func (z *zlogImpl) WithCallStack() ZLogger {
-	callStack := make([]string, 0)
-	for skip := 2; skip < z.maxCallStackDepth; skip++ {
-		current, ok := getSourceString(skip)
-		if !ok {
-			continue
-		}
-		callStack = append(callStack, current)
-		if strings.HasPrefix(current, "#main.main") {
-			break
-		}
-	}
-	return z.appendAttr(slog.Any("callstack", callStack))
+	depth := z.maxCallStackDepth - 2
+	callStack := captureCallStack(2, depth, z.frameFilter)
+	z.hasCallStack = true
+	return z.appendAttr(callStackAttr(callStack, depth, z.frameFilter, z.frameStyle))
+}
+
+// WithAllGoroutines adds the stacks of every goroutine to the log entry, to diagnose deadlocks and hangs.
+// Goroutines with the same state and stack are grouped, largest group first; the stacks are captured
+// immediately but only parsed when the entry is written. Capturing stops the world briefly, so keep it
+// off hot paths.
+//
+// Example:
+//
+//	Error().WithAllGoroutines().Message("Shutdown timed out")
+//	// Output: {"level":"error","time":"2024-03-07T10:00:00Z","goroutines":[{"count":12,"state":"chan receive","ids":[7,8,...],"stack":[...],"createdBy":{...}}],"message":"Shutdown timed out"}
+func (z *zlogImpl) WithAllGoroutines() ZLogger {
+	z.hasGoroutines = true
+	return z.appendAttr(goroutinesAttr(z.frameStyle.paths))
 }
 
 // Alert marks the log entry as requiring immediate attention.
@@ -461,6 +828,7 @@ func (z *zlogImpl) WithCallStack() ZLogger {
 //	Error().Alert().Message("System running out of disk space")
 //	// Output: {"level":"error","time":"2024-03-07T10:00:00Z","alert":true,"message":"System running out of disk space"}
 func (z *zlogImpl) Alert() ZLogger {
+	z.alert = true
 	return z.appendAttr(slog.Bool("alert", true))
 }
 
@@ -473,7 +841,8 @@ func (z *zlogImpl) Alert() ZLogger {
 //	Info().KeyValue("status", "healthy").Message("Health check completed")
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","status":"healthy","message":"Health check completed"}
 func (z *zlogImpl) Message(message string) {
-	z.logger.Info(message, z.attrs...)
+	z.emit(message, true)
+	z.release()
 }
 
 // Msg is an alias for Message.
@@ -484,7 +853,8 @@ func (z *zlogImpl) Message(message string) {
 //	Info().KeyValue("status", "healthy").Msg("Health check completed")
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","status":"healthy","message":"Health check completed"}
 func (z *zlogImpl) Msg(message string) {
-	z.logger.Info(message, z.attrs...)
+	z.emit(message, true)
+	z.release()
 }
 
 // Messagef emits the log entry with a formatted message.
@@ -496,7 +866,8 @@ func (z *zlogImpl) Msg(message string) {
 //	Info().Messagef("Processed %d items in %v", 100, time.Second*2)
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","message":"Processed 100 items in 2s"}
 func (z *zlogImpl) Messagef(format string, args ...any) {
-	z.logger.Info(fmt.Sprintf(format, args...), z.attrs...)
+	z.emit(fmt.Sprintf(format, args...), true)
+	z.release()
 }
 
 // Msgf is an alias for Messagef.
@@ -507,13 +878,15 @@ func (z *zlogImpl) Messagef(format string, args ...any) {
 //	Info().Msgf("Processed %d items in %v", 100, time.Second*2)
 //	// Output: {"level":"info","time":"2024-03-07T10:00:00Z","message":"Processed 100 items in 2s"}
 func (z *zlogImpl) Msgf(format string, args ...any) {
-	z.logger.Info(fmt.Sprintf(format, args...), z.attrs...)
+	z.emit(fmt.Sprintf(format, args...), true)
+	z.release()
 }
 
 // Fatal logs the message at error level and then terminates the program with exit code 1.
 // This is a terminal operation that should be used only when the application cannot continue running.
 // After calling Fatal, the program will exit immediately.
-// The method ensures that the log message is written to the output before exiting.
+// The method ensures that the log message is written to the output before exiting,
+// even if the minimum level or a segment rule would discard the entry.
 // Note: Deferred functions will NOT be executed as os.Exit(1) is called directly.
 //
 // Example:
@@ -522,19 +895,16 @@ func (z *zlogImpl) Msgf(format string, args ...any) {
 //	// Output: {"level":"error","time":"2024-03-07T10:00:00Z","message":"Failed to initialize database connection"}
 //	// Then exits with status 1
 func (z *zlogImpl) Fatal(message string) {
-	z.Message(message)
-	// Ensure logs are written before exit
-	if handler, ok := z.logger.Handler().(interface{ Sync() error }); ok {
-		_ = handler.Sync()
-	}
-	os.Stdout.Sync()
-	os.Exit(1)
+	z.fatal = true
+	z.emit(message, false)
+	z.exit()
 }
 
 // Fatalf logs the formatted message at error level and then terminates the program with exit code 1.
 // This is a terminal operation that should be used only when the application cannot continue running.
 // After calling Fatalf, the program will exit immediately.
-// The method ensures that the log message is written to the output before exiting.
+// The method ensures that the log message is written to the output before exiting,
+// even if the minimum level or a segment rule would discard the entry.
 // Note: Deferred functions will NOT be executed as os.Exit(1) is called directly.
 //
 // Example:
@@ -543,120 +913,224 @@ func (z *zlogImpl) Fatal(message string) {
 //	// Output: {"level":"error","time":"2024-03-07T10:00:00Z","message":"Failed to initialize database connection"}
 //	// Then exits with status 1
 func (z *zlogImpl) Fatalf(format string, args ...any) {
-	z.Messagef(format, args...)
-	// Ensure logs are written before exit
+	z.fatal = true
+	z.emit(fmt.Sprintf(format, args...), false)
+	z.exit()
+}
+
+// exit flushes the output of the entry and terminates the program with exit code 1
+func (z *zlogImpl) exit() {
+	// Ensure logs are written before exit, including those buffered by writers such as HTTPWriter
 	if handler, ok := z.logger.Handler().(interface{ Sync() error }); ok {
 		_ = handler.Sync()
 	}
+	if output, ok := z.active.output.(interface{ Sync() error }); ok {
+		_ = output.Sync()
+	}
 	os.Stdout.Sync()
 	os.Exit(1)
 }
 
+// disabledLogger is returned for entries below the minimum level.
+// Every chain method is a no-op, but Fatal and Fatalf still write their message and terminate the program.
+type disabledLogger struct {
+	level int8 // Level of the entry, a single byte so returning it as a ZLogger does not allocate
+}
+
+// newDisabledLogger returns a disabledLogger for level, levels beyond the range of int8 are clamped
+func newDisabledLogger(level slog.Level) disabledLogger {
+	return disabledLogger{level: int8(max(math.MinInt8, min(math.MaxInt8, level)))}
+}
+
+func (d disabledLogger) Context(context.Context, []string) ZLogger { return d }
+func (d disabledLogger) Segment(string, ...string) ZLogger         { return d }
+func (d disabledLogger) WithError(error) ZLogger                   { return d }
+func (d disabledLogger) Err(error) ZLogger                         { return d }
+func (d disabledLogger) Alert() ZLogger                            { return d }
+func (d disabledLogger) WithSource() ZLogger                       { return d }
+func (d disabledLogger) WithSourceSkip(int) ZLogger                { return d }
+func (d disabledLogger) WithCallStack() ZLogger                    { return d }
+func (d disabledLogger) WithAllGoroutines() ZLogger                { return d }
+func (d disabledLogger) KeyValue(string, string) ZLogger           { return d }
+func (d disabledLogger) Every(time.Duration) ZLogger               { return d }
+func (d disabledLogger) Message(string)                            {}
+func (d disabledLogger) Msg(string)                                {}
+func (d disabledLogger) Messagef(string, ...any)                   {}
+func (d disabledLogger) Msgf(string, ...any)                       {}
+
+// Fatal writes the message with the current configuration, bypassing the level filters, and exits.
+// The attributes added to the disabled entry are not kept.
+func (d disabledLogger) Fatal(message string) {
+	z := newEntry(pinnedConfig(), slog.Level(d.level), nil)
+	z.fatal = true
+	z.emit(message, false)
+	z.exit()
+}
+
+// Fatalf is the formatted form of Fatal.
+func (d disabledLogger) Fatalf(format string, args ...any) {
+	z := newEntry(pinnedConfig(), slog.Level(d.level), nil)
+	z.fatal = true
+	z.emit(fmt.Sprintf(format, args...), false)
+	z.exit()
+}
+
+// maxPooledAttrs is the attribute capacity above which entries are left to the garbage collector
+// instead of being pooled, so one large entry does not pin its memory
+const maxPooledAttrs = 64
+
+// entryPool recycles the entries returned by the level functions, see release
+var entryPool = sync.Pool{New: func() any {
+	return &zlogImpl{attrs: make([]slog.Attr, 0, 8), output: make([]slog.Attr, 0, 10)}
+}}
+
+// release unpins the output of the entry and returns it to entryPool once written. It must not be used afterwards,
+// which is why only the terminal methods release entries.
+func (z *zlogImpl) release() {
+	z.active.unpinOutput()
+	if cap(z.attrs) > maxPooledAttrs || cap(z.output) > maxPooledAttrs+5 {
+		return
+	}
+	clear(z.attrs)
+	clear(z.output)
+	*z = zlogImpl{attrs: z.attrs[:0], output: z.output[:0]}
+	entryPool.Put(z)
+}
+
 func (z *zlogImpl) appendAttr(attr slog.Attr) ZLogger {
 	z.attrs = append(z.attrs, attr)
 	return z
 }
 
-func (z *zlogImpl) appendAttrs(attrs ...any) ZLogger {
+func (z *zlogImpl) appendAttrs(attrs ...slog.Attr) ZLogger {
 	z.attrs = append(z.attrs, attrs...)
 	return z
 }
 
-// applyAutoFeatures applies automatic features based on global config
-func (z *zlogImpl) applyAutoFeatures(level slog.Level) ZLogger {
-	var autoSource, autoCallStack bool
+// emitCallerSkip is the callerPC skip of the code calling a terminal method, seen from autoAttrs:
+// callerPC, autoAttrs, emit, the terminal method (Message, Fatal, ...), the caller.
+const emitCallerSkip = 4
 
-	switch level {
-	case slog.LevelDebug:
-		autoSource = globalConfig.Debug.AutoSource
-		autoCallStack = globalConfig.Debug.AutoCallStack
-	case slog.LevelInfo:
-		autoSource = globalConfig.Info.AutoSource
-		autoCallStack = globalConfig.Info.AutoCallStack
-	case slog.LevelWarn:
-		autoSource = globalConfig.Warn.AutoSource
-		autoCallStack = globalConfig.Warn.AutoCallStack
-	case slog.LevelError:
-		autoSource = globalConfig.Error.AutoSource
-		autoCallStack = globalConfig.Error.AutoCallStack
+// emit writes the entry if its level passes the rule matching its segment and, when throttled, the rate limit,
+// dedup and sampling policies, after the automatic source and call stack. Every terminal method calls it directly
+// so the caller is always emitCallerSkip up. Fatal entries are neither filtered by level nor throttled.
+func (z *zlogImpl) emit(message string, throttled bool) {
+	active := z.active
+	rule := z.resolveRule(active)
+	minLevel := active.MinLevel
+	if rule.Level != nil {
+		minLevel = rule.Level
 	}
-
-	if autoSource {
-		if source, ok := getSourceString(3); ok {
-			z.appendAttr(slog.String("source", source))
-		}
+	if minLevel != nil && z.level < *minLevel && !z.fatal {
+		return
 	}
-
-	if autoCallStack {
-		callStack := make([]string, 0)
-		for skip := 3; skip < z.maxCallStackDepth; skip++ {
-			current, ok := getSourceString(skip)
-			if !ok {
-				continue
+	var extra []slog.Attr
+	var limited *limitState
+	if throttled {
+		window := active.DedupWindow
+		if z.every > 0 {
+			window = z.every
+		}
+		if active.RateLimit != nil || window > 0 {
+			var ok bool
+			if extra, limited, ok = active.limiter.allow(z, message, active.RateLimit, window); !ok {
+				return
 			}
-			callStack = append(callStack, current)
-			if strings.HasPrefix(current, "#main.main") {
-				break
+		}
+		if active.sampler != nil {
+			if !active.sampler.allow(z.level, z.segment, message) {
+				active.sampler.schedule()
+				return
 			}
+			active.sampler.report(z.logger)
 		}
-		z.appendAttr(slog.Any("callstack", callStack))
 	}
+	attrs := z.autoAttrs(active, rule)
+	if limited != nil {
+		limited.record(attrs)
+	}
+	if len(extra) > 0 {
+		attrs = append(attrs, extra...)
+	}
+	z.logger.LogAttrs(context.Background(), z.level, message, attrs...)
+}
 
-	return z
+// resolveRule combines the rules applying to the entry, the segment rule taking precedence over the package rule
+func (z *zlogImpl) resolveRule(active *activeConfig) Rule {
+	var rule Rule
+	if segment := matchSegmentRule(active.segments, z.segment); segment != nil {
+		rule = segment.Rule
+	}
+	if z.packageRule != nil {
+		rule = rule.inherit(*z.packageRule)
+	}
+	return rule
 }
 
-// getMaxCallStackDepth returns the max call stack depth for the given level
-// If config value is 0, returns the default value
-func getMaxCallStackDepth(level slog.Level) int {
-	switch level {
-	case slog.LevelDebug:
-		if globalConfig.Debug.MaxCallStackDepth > 0 {
-			return globalConfig.Debug.MaxCallStackDepth
+// autoAttrs returns the entry's attributes preceded by the automatic source, call stack, goroutine ID
+// and pprof labels configured for its level, as overridden by its rules, and the goroutine dump
+// configured for Fatal and Alert entries
+func (z *zlogImpl) autoAttrs(active *activeConfig, rule Rule) []slog.Attr {
+	levelConf := active.level(z.level)
+	autoSource, autoCallStack := enabled(levelConf.AutoSource), enabled(levelConf.AutoCallStack)
+	maxDepth := active.maxCallStackDepth(z.level)
+	if rule.AutoSource != nil {
+		autoSource = *rule.AutoSource
+	}
+	if rule.AutoCallStack != nil {
+		autoCallStack = *rule.AutoCallStack
+	}
+	if rule.MaxCallStackDepth > 0 {
+		maxDepth = rule.MaxCallStackDepth
+	}
+	autoSource = autoSource && !z.hasSource
+	autoCallStack = autoCallStack && !z.hasCallStack
+	autoLabels := enabled(levelConf.AutoPprofLabels) && z.ctx != nil
+	autoGoroutines := active.GoroutineDump != nil && !z.hasGoroutines &&
+		((z.fatal && active.GoroutineDump.OnFatal) || (z.alert && active.GoroutineDump.OnAlert))
+	if !autoSource && !autoCallStack && !enabled(levelConf.AutoGoroutineID) && !autoLabels && !autoGoroutines {
+		return z.attrs
+	}
+
+	attrs := z.output[:0]
+	if autoSource {
+		source, ok := z.callerPC, z.callerPC != 0
+		if !ok {
+			source, ok = callerPC(emitCallerSkip) // Fatal of a disabled entry
 		}
-		return defaultCallStackDepths[level]
-	case slog.LevelInfo:
-		if globalConfig.Info.MaxCallStackDepth > 0 {
-			return globalConfig.Info.MaxCallStackDepth
+		if ok {
+			attrs = append(attrs, sourceAttr(source, levelConf.frameStyle()))
 		}
-		return defaultCallStackDepths[level]
-	case slog.LevelWarn:
-		if globalConfig.Warn.MaxCallStackDepth > 0 {
-			return globalConfig.Warn.MaxCallStackDepth
+	}
+	if autoCallStack {
+		depth := maxDepth - emitCallerSkip + 1
+		callStack := captureCallStack(emitCallerSkip, depth, active.frameFilter)
+		attrs = append(attrs, callStackAttr(callStack, depth, active.frameFilter, levelConf.frameStyle()))
+	}
+	if enabled(levelConf.AutoGoroutineID) {
+		if id, ok := goroutineID(); ok {
+			attrs = append(attrs, slog.Uint64("goroutine", id))
 		}
-		return defaultCallStackDepths[level]
-	case slog.LevelError:
-		if globalConfig.Error.MaxCallStackDepth > 0 {
-			return globalConfig.Error.MaxCallStackDepth
+	}
+	if autoLabels {
+		if labels := pprofLabels(z.ctx); len(labels) > 0 {
+			attrs = append(attrs, slog.Attr{Key: "pprof_labels", Value: slog.GroupValue(labels...)})
 		}
-		return defaultCallStackDepths[level]
-	default:
-		return 5
 	}
+	if autoGoroutines {
+		attrs = append(attrs, goroutinesAttr(levelConf.frameStyle().paths))
+	}
+	z.output = append(attrs, z.attrs...)
+	return z.output
 }
 
-func getSourceString(skip int) (string, bool) {
-	pc, file, line, ok := runtime.Caller(skip)
-	if !ok {
-		return "", false
-	}
-
-	var funcName string
-	fn := runtime.FuncForPC(pc)
-	if fn == nil {
-		funcName = "?"
-	} else {
-		funcName = fn.Name()
-		moduleSeparator := strings.LastIndex(funcName, "/")
-		if moduleSeparator != -1 {
-			funcName = funcName[moduleSeparator+1:]
-		}
+// maxCallStackDepth returns the configured call stack depth of level, or its default
+func (c Config) maxCallStackDepth(level slog.Level) int {
+	if maxDepth := c.level(level).MaxCallStackDepth; maxDepth > 0 {
+		return maxDepth
+	}
+	if maxDepth, ok := defaultCallStackDepths[level]; ok {
+		return maxDepth
 	}
-	var b strings.Builder
-	b.WriteByte('#')
-	b.WriteString(funcName)
-	b.WriteString(" @ ")
-	b.WriteString(file)
-	b.WriteByte(':')
-	b.WriteString(strconv.FormatInt(int64(line), 10))
-	return b.String(), true
+	return 5
 }
@@ -8,8 +8,15 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/GokselKUCUKSAHIN/zlog"
 )
@@ -467,6 +474,41 @@ func TestWithCallStack(t *testing.T) {
 	}
 }
 
+//go:noinline
+func logWithCallStack() {
+	zlog.Error().WithCallStack().Message("from helper")
+}
+
+// inlinedWrapper is small enough to be inlined into its callers
+func inlinedWrapper() {
+	logWithCallStack()
+}
+
+// TestCallStackInlinedFrames tests that inlined calls keep their own frames in call stacks
+func TestCallStackInlinedFrames(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	inlinedWrapper()
+	inlinedWrapper() // Second time from the frame cache
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		logData, err := parseLogOutput(line)
+		if err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		callstack, _ := logData["callstack"].([]interface{})
+		if len(callstack) < 3 {
+			t.Fatalf("Expected at least 3 frames, got %v", callstack)
+		}
+		for i, function := range []string{"logWithCallStack", "inlinedWrapper", "TestCallStackInlinedFrames"} {
+			if frame, _ := callstack[i].(string); !strings.Contains(frame, "."+function+" @ ") || !strings.Contains(frame, "zlog_test.go") {
+				t.Errorf("Expected frame %d in %s, got %q", i, function, frame)
+			}
+		}
+	}
+}
+
 // TestAutoSourceConfig tests automatic source configuration
 func TestAutoSourceConfig(t *testing.T) {
 	tests := []struct {
@@ -538,6 +580,38 @@ func TestAutoSourceConfig(t *testing.T) {
 	}
 }
 
+// finishEntry writes an entry created by its caller, like a request logging helper
+func finishEntry(logger zlog.ZLogger) {
+	logger.KeyValue("helper", "yes").Msg("finished in a helper")
+}
+
+// TestAutoSourceFinishedInHelper tests that the automatic source is the level function call,
+// not the helper writing the entry
+func TestAutoSourceFinishedInHelper(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+	defer zlog.SetConfig(zlog.Configure())
+
+	for name, config := range map[string]zlog.Config{
+		"level":   zlog.Configure(zlog.AutoSourceConfig(slog.LevelInfo, true)),
+		"segment": zlog.Configure(zlog.SegmentAutoSourceConfig("api", true)),
+	} {
+		buf.Reset()
+		zlog.SetConfig(config)
+		_, _, line, _ := runtime.Caller(0)
+		finishEntry(zlog.Info().Segment("api"))
+
+		logData, err := parseLogOutput(buf.String())
+		if err != nil {
+			t.Fatalf("Failed to parse log output: %v", err)
+		}
+		source, _ := logData["source"].(string)
+		if !strings.Contains(source, "TestAutoSourceFinishedInHelper @ ") || !strings.HasSuffix(source, "zlog_test.go:"+strconv.Itoa(line+1)) {
+			t.Errorf("%s: expected the source at line %d of this test, got %q", name, line+1, source)
+		}
+	}
+}
+
 // TestAutoCallStackConfig tests automatic call stack configuration
 func TestAutoCallStackConfig(t *testing.T) {
 	tests := []struct {
@@ -652,6 +726,32 @@ func TestMaxCallStackDepthConfig(t *testing.T) {
 	}
 }
 
+// TestMinLevelConfig tests that entries below the minimum level are discarded
+func TestMinLevelConfig(t *testing.T) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelWarn),
+	))
+
+	zlog.Debug().KeyValue("key", "value").Message("debug")
+	zlog.Info().WithCallStack().Message("info")
+	zlog.Warn().Message("warn")
+
+	output := buf.String()
+	if strings.Contains(output, "debug") || strings.Contains(output, `"info"`) {
+		t.Errorf("Expected entries below Warn to be discarded, got: %s", output)
+	}
+	logData, err := parseLogOutput(output)
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if logData["msg"] != "warn" {
+		t.Errorf("Expected msg='warn', got %v", logData["msg"])
+	}
+}
+
 // TestChainedMethods tests method chaining
 func TestChainedMethods(t *testing.T) {
 	var buf bytes.Buffer
@@ -705,6 +805,43 @@ func TestChainedMethods(t *testing.T) {
 	}
 }
 
+// fatalEnv selects the test run in the child process by runFatal
+const fatalEnv = "ZLOG_TEST_FATAL"
+
+// runFatal runs test in a child process with fatalEnv set to its name, so that it can call Fatal,
+// and returns the first line of its output after checking that it exited with status 1.
+// env adds variables ("KEY=value") to the environment of the child.
+func runFatal(t *testing.T, test string, env ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^"+test+"$")
+	cmd.Env = append(append(os.Environ(), fatalEnv+"="+test), env...)
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("Expected exit code 1, got %v with output %q", err, output)
+	}
+	line, _, _ := strings.Cut(string(output), "\n")
+	return line
+}
+
+// TestFatalBelowMinLevel tests that Fatal writes its entry even when its level is discarded
+func TestFatalBelowMinLevel(t *testing.T) {
+	if os.Getenv(fatalEnv) == t.Name() {
+		zlog.SetOutputWriter(os.Stdout)
+		zlog.SetConfig(zlog.Configure(zlog.MinLevelConfig(zlog.LevelCritical)))
+		zlog.Error().KeyValue("dropped", "yes").Fatalf("giving up after %d tries", 3)
+		return
+	}
+
+	logData, err := parseLogOutput(runFatal(t, t.Name()))
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if logData["msg"] != "giving up after 3 tries" || logData["level"] != "ERROR" {
+		t.Errorf("Expected the fatal entry at error level, got %v", logData)
+	}
+}
+
 // TestPanicFunction tests Panic function
 func TestPanicFunction(t *testing.T) {
 	defer func() {
@@ -1172,6 +1309,32 @@ func BenchmarkLogWithKeyValue(b *testing.B) {
 	}
 }
 
+func BenchmarkDisabledLevel(b *testing.B) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelInfo),
+	))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		zlog.Debug().Segment("api").KeyValue("key", "value").Message("benchmark test")
+	}
+}
+
+func BenchmarkParallelLog(b *testing.B) {
+	setupTestLogger(&bytes.Buffer{})
+	zlog.SetOutputWriter(io.Discard)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			zlog.Info().Segment("api").KeyValue("key", "value").Message("benchmark test")
+		}
+	})
+}
+
 func BenchmarkComplexLog(b *testing.B) {
 	var buf bytes.Buffer
 	setupTestLogger(&buf)
@@ -1228,6 +1391,52 @@ func BenchmarkAutoSourceConfig(b *testing.B) {
 	}
 }
 
+func BenchmarkPackageRules(b *testing.B) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.MinLevelConfig(slog.LevelInfo),
+		zlog.PackageLevelConfig("github.com/acme/billing/...", slog.LevelDebug),
+	))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		zlog.Debug().Message("filtered by package rule")
+	}
+}
+
+func BenchmarkSampledOut(b *testing.B) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.SampleMessagesConfig(time.Hour, 1, 0),
+	))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		zlog.Info().Message("dropped by sampling")
+	}
+}
+
+func BenchmarkSampledOutWithCallStack(b *testing.B) {
+	var buf bytes.Buffer
+	setupTestLogger(&buf)
+
+	zlog.SetConfig(zlog.Configure(
+		zlog.SampleMessagesConfig(time.Hour, 1, 0),
+	))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		zlog.Error().WithCallStack().WithSource().Message("dropped by sampling")
+	}
+}
+
 func BenchmarkAutoCallStackConfig(b *testing.B) {
 	var buf bytes.Buffer
 	setupTestLogger(&buf)
@@ -1408,33 +1617,159 @@ func TestEdgeCaseVeryLongCallStack(t *testing.T) {
 	}
 }
 
-// TestEdgeCaseConfigChangeDoesNotAffectExistingLoggers tests if config change requires logger reinit
+// TestEdgeCaseConfigChangeDoesNotAffectExistingLoggers tests that an entry is written entirely with the
+// configuration active when it was created, never mixing it with a later one
 func TestEdgeCaseConfigChangeDoesNotAffectExistingLoggers(t *testing.T) {
 	var buf bytes.Buffer
 	setupTestLogger(&buf)
 
 	// Create logger instance before config
-	logger := zlog.Error()
+	logger := zlog.Error().KeyValue("password", "hunter2")
 
-	// Change config
+	// Change config and output
+	var newBuf bytes.Buffer
+	zlog.SetOutputWriter(&newBuf)
 	zlog.SetConfig(zlog.Configure(
 		zlog.AutoSourceConfig(slog.LevelError, true),
+		zlog.RedactKeysConfig("password"),
 	))
 
 	// Use the logger created before config change
-	logger.Message("test")
+	logger.Message("old")
+	logData, err := parseLogOutput(buf.String())
+	if err != nil {
+		t.Fatalf("Failed to parse log output: %v", err)
+	}
+	if _, hasSource := logData["source"]; hasSource || logData["password"] != "hunter2" {
+		t.Errorf("Expected the entry to use the config it was created with, got %v", logData)
+	}
 
-	output := buf.String()
-	logData, err := parseLogOutput(output)
+	// Entries created afterwards use the new config
+	zlog.Error().KeyValue("password", "hunter2").Message("new")
+	logData, err = parseLogOutput(newBuf.String())
 	if err != nil {
 		t.Fatalf("Failed to parse log output: %v", err)
 	}
+	if _, hasSource := logData["source"]; !hasSource || logData["password"] == "hunter2" {
+		t.Errorf("Expected source and redaction from the new config, got %v", logData)
+	}
+}
+
+// logConcurrently logs from workers goroutines until stop is closed
+func logConcurrently(workers int, stop <-chan struct{}) (*sync.WaitGroup, *atomic.Int64) {
+	var wg sync.WaitGroup
+	var logged atomic.Int64
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				zlog.Info().Segment("worker").KeyValue("password", "hunter2").Message("working")
+				zlog.Error().WithCallStack().Msgf("failed %d", 1)
+				logged.Add(2)
+				time.Sleep(10 * time.Microsecond)
+			}
+		}()
+	}
+	return &wg, &logged
+}
 
-	// The logger was created before config, so it might not have auto-source
-	// This documents the behavior: config applies at logger creation time
-	_, hasSource := logData["source"]
-	t.Logf("Logger created before config change has source: %v", hasSource)
-	// This is expected behavior - config is applied when logger is created
+// checkLines checks that output holds only complete entries written by logConcurrently and returns their count
+func checkLines(t *testing.T, name, output string) int64 {
+	t.Helper()
+	var count int64
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		count++
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Broken entry in %s: %q", name, line)
+		}
+		if entry["msg"] != "working" && entry["msg"] != "failed 1" {
+			t.Fatalf("Unexpected entry in %s: %q", name, line)
+		}
+	}
+	return count
+}
+
+// TestConcurrentReconfiguration tests that SetConfig and SetOutputWriter can run while other goroutines log,
+// run it with -race
+func TestConcurrentReconfiguration(t *testing.T) {
+	defer setupTestLogger(&bytes.Buffer{})
+
+	t.Run("SetConfig and SetOutputWriter", func(t *testing.T) {
+		var first, second syncBuffer
+		zlog.SetOutputWriter(&first)
+		stop := make(chan struct{})
+		wg, _ := logConcurrently(4, stop)
+		for first.String() == "" {
+			time.Sleep(time.Millisecond) // Reconfigure while the workers log
+		}
+
+		configs := []zlog.Config{
+			zlog.Configure(),
+			zlog.Configure(zlog.AutoSourceConfig(slog.LevelInfo, true), zlog.FrameFormatConfig(slog.LevelInfo, zlog.FrameStructured)),
+			zlog.Configure(zlog.RedactKeysConfig("password"), zlog.MaxStringLengthConfig(4)),
+			zlog.Configure(zlog.SegmentLevelConfig("worker", slog.LevelWarn), zlog.DropStdlibFramesConfig()),
+			zlog.Configure(zlog.SampleLevelConfig(slog.LevelInfo, 0.5), zlog.DedupConfig(time.Millisecond)),
+		}
+		for i := 0; i < 200; i++ {
+			zlog.SetConfig(configs[i%len(configs)])
+			if i%2 == 0 {
+				zlog.SetOutputWriter(&first)
+			} else {
+				zlog.SetOutputWriter(&second)
+			}
+			_ = zlog.CurrentConfig()
+		}
+		close(stop)
+		wg.Wait()
+
+		checkLines(t, "first writer", first.String())
+		checkLines(t, "second writer", second.String())
+	})
+
+	t.Run("Output files", func(t *testing.T) {
+		dir := t.TempDir()
+		paths := []string{filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")}
+		zlog.SetConfig(zlog.Configure(zlog.OutputConfig(paths[1])))
+		stop := make(chan struct{})
+		wg, logged := logConcurrently(4, stop)
+
+		// An entry created before the output changes is still written to the file it was created for
+		held := zlog.Info().Segment("worker")
+		zlog.SetConfig(zlog.Configure(zlog.OutputConfig(paths[0])))
+		held.Message("working")
+		logged.Add(1)
+
+		for i := 0; i < 50; i++ {
+			zlog.SetConfig(zlog.Configure(zlog.OutputConfig(paths[i%2])))
+			time.Sleep(100 * time.Microsecond)
+		}
+		close(stop)
+		wg.Wait()
+		zlog.SetOutputWriter(&bytes.Buffer{}) // Closes the last file
+
+		// Every entry lands in one of the files, none is lost to a file closed under it
+		var written int64
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", path, err)
+			}
+			written += checkLines(t, path, string(data))
+		}
+		if written != logged.Load() {
+			t.Errorf("Expected %d entries in the files, got %d", logged.Load(), written)
+		}
+	})
 }
 
 // TestEdgeCaseNilContextValue tests context value that is explicitly nil
@@ -1488,3 +1823,37 @@ func TestEdgeCaseMultipleAutoFeatures(t *testing.T) {
 		t.Error("Expected callstack to be present")
 	}
 }
+
+// TestHotPathAllocations tests that disabled entries do not allocate and enabled ones reuse pooled entries
+func TestHotPathAllocations(t *testing.T) {
+	setupTestLogger(&bytes.Buffer{})
+	zlog.SetOutputWriter(io.Discard)
+	defer zlog.SetConfig(zlog.Configure())
+
+	zlog.SetConfig(zlog.Configure(zlog.MinLevelConfig(slog.LevelInfo)))
+	disabled := testing.AllocsPerRun(1000, func() {
+		zlog.Debug().Segment("api").KeyValue("key", "value").Message("disabled")
+	})
+	if disabled != 0 {
+		t.Errorf("Expected no allocations for disabled levels, got %v", disabled)
+	}
+
+	// No allocation once the pool is warm, but the race detector randomly drops pooled entries
+	// and a new entry takes 3 allocations
+	enabled := testing.AllocsPerRun(1000, func() {
+		zlog.Info().Segment("api").KeyValue("key", "value").Message("enabled")
+	})
+	if enabled > 3 {
+		t.Errorf("Expected at most 3 allocations per enabled entry, got %v", enabled)
+	}
+
+	// A dropped entry only keeps the program counters of its source, which are never formatted
+	zlog.SetConfig(zlog.Configure(zlog.SampleMessagesConfig(time.Hour, 1, 0)))
+	zlog.Info().Message("sampled")
+	sampled := testing.AllocsPerRun(1000, func() {
+		zlog.Info().WithSource().Message("sampled")
+	})
+	if sampled > 3 {
+		t.Errorf("Expected at most 3 allocations per sampled out entry with source, got %v", sampled)
+	}
+}